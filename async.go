@@ -0,0 +1,209 @@
+package logecho
+
+import (
+	"bufio"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Async sets asynchronous buffered writing of logs.
+//
+// Log calls only queue the encoded entry into a bounded buffer, and
+// a background flusher writes them to the outputs. Call
+// Logecho.Close on shutdown to drain the buffer:
+//
+//	if err := e.Shutdown(ctx); err != nil {
+//		// ...
+//	}
+//	logecho.Logger.Close()
+type Async struct {
+	// BufferSize is the maximum number of entries waiting to be
+	// written to each output.
+	//
+	// Default is 1024
+	BufferSize int
+
+	// FlushInterval is the interval the background flusher flushes
+	// the written entries to the outputs.
+	//
+	// Default is 1 second
+	FlushInterval time.Duration
+
+	// Block makes log calls wait for room when the buffer is full.
+	//
+	// Default is false, who drops the entry and counts it on
+	// Logecho.Dropped
+	Block bool
+}
+
+const (
+	defaultAsyncBufferSize    = 1024
+	defaultAsyncFlushInterval = time.Second
+	// asyncWriteBufferSize is the size of the flusher write buffer
+	asyncWriteBufferSize = 256 * 1024
+)
+
+// getBufferSize returns 1024 as default when BufferSize is not set
+func (a Async) getBufferSize() int {
+	if a.BufferSize <= 0 {
+		return defaultAsyncBufferSize
+	}
+
+	return a.BufferSize
+}
+
+// getFlushInterval returns 1 second as default when FlushInterval
+// is not set
+func (a Async) getFlushInterval() time.Duration {
+	if a.FlushInterval <= 0 {
+		return defaultAsyncFlushInterval
+	}
+
+	return a.FlushInterval
+}
+
+// asyncWriter is a zapcore.WriteSyncer who queues writes to a
+// background flusher
+type asyncWriter struct {
+	ws      zapcore.WriteSyncer
+	block   bool
+	dropped *uint64
+
+	entries chan []byte
+	syncs   chan chan error
+	done    chan struct{}
+
+	// m guards closed. Writers hold it in read mode, so Close
+	// waits writers who are blocked on a full buffer
+	m      sync.RWMutex
+	closed bool
+}
+
+// newAsyncWriter starts the background flusher of ws. Dropped entries
+// are counted on dropped
+func newAsyncWriter(ws zapcore.WriteSyncer, cfg Async, dropped *uint64) *asyncWriter {
+	w := &asyncWriter{
+		ws:      ws,
+		block:   cfg.Block,
+		dropped: dropped,
+		entries: make(chan []byte, cfg.getBufferSize()),
+		syncs:   make(chan chan error),
+		done:    make(chan struct{}),
+	}
+
+	go w.run(cfg.getFlushInterval())
+
+	return w
+}
+
+// Write queues a copy of p. When the buffer is full it drops p or
+// waits for room, by the Block policy
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.m.RLock()
+	defer w.m.RUnlock()
+
+	if w.closed {
+		return w.ws.Write(p)
+	}
+
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	if w.block {
+		w.entries <- entry
+		return len(p), nil
+	}
+
+	select {
+	case w.entries <- entry:
+	default:
+		atomic.AddUint64(w.dropped, 1)
+	}
+
+	return len(p), nil
+}
+
+// Sync waits the flusher write the queued entries and sync the output
+func (w *asyncWriter) Sync() error {
+	w.m.RLock()
+	defer w.m.RUnlock()
+
+	if w.closed {
+		return w.ws.Sync()
+	}
+
+	ack := make(chan error)
+	w.syncs <- ack
+
+	return <-ack
+}
+
+// Close drains the queued entries, syncs the output and stops the
+// flusher. Later writes go straight to the output
+func (w *asyncWriter) Close() error {
+	w.m.Lock()
+	if w.closed {
+		w.m.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.entries)
+	w.m.Unlock()
+
+	<-w.done
+
+	return w.ws.Sync()
+}
+
+// run is the background flusher
+func (w *asyncWriter) run(interval time.Duration) {
+	defer close(w.done)
+
+	buf := bufio.NewWriterSize(w.ws, asyncWriteBufferSize)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// drain writes every queued entry without waiting
+	drain := func() {
+		for {
+			select {
+			case entry, ok := <-w.entries:
+				if !ok {
+					return
+				}
+				buf.Write(entry)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				buf.Flush()
+				return
+			}
+			buf.Write(entry)
+		case <-ticker.C:
+			buf.Flush()
+		case ack := <-w.syncs:
+			drain()
+			err := buf.Flush()
+			if syncErr := w.ws.Sync(); err == nil {
+				err = syncErr
+			}
+			ack <- err
+		}
+	}
+}
+
+// Dropped returns how many entries were dropped because the Async
+// buffer was full
+func (z *Logecho) Dropped() uint64 {
+	return atomic.LoadUint64(&z.dropped)
+}
@@ -0,0 +1,148 @@
+package logecho
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter blocks writes until its gate opens
+type gateWriter struct {
+	gate chan struct{}
+
+	// writing is closed on the first write
+	writing chan struct{}
+	once    sync.Once
+
+	m sync.Mutex
+	b bytes.Buffer
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{gate: make(chan struct{}), writing: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.once.Do(func() { close(g.writing) })
+	<-g.gate
+
+	g.m.Lock()
+	defer g.m.Unlock()
+	return g.b.Write(p)
+}
+
+func (g *gateWriter) String() string {
+	g.m.Lock()
+	defer g.m.Unlock()
+	return g.b.String()
+}
+
+func TestAsync(t *testing.T) {
+	t.Run("should drain buffer on Close", func(t *testing.T) {
+		b := newGateWriter()
+		close(b.gate)
+
+		z, err := New(Config{
+			Outputs: []Output{{Writer: b}},
+			Async:   &Async{FlushInterval: time.Hour},
+		})
+		if err != nil {
+			t.Fatal("expected no error but got", err)
+		}
+
+		for i := 0; i < 100; i++ {
+			z.NoContext().Info("async")
+		}
+		z.Close()
+
+		if lines := decodeLines(t, bytes.NewBufferString(b.String())); len(lines) != 100 {
+			t.Fatal("expected 100 log lines but got", len(lines))
+		}
+	})
+
+	t.Run("should flush on interval", func(t *testing.T) {
+		b := newGateWriter()
+		close(b.gate)
+
+		z, _ := New(Config{
+			Outputs: []Output{{Writer: b}},
+			Async:   &Async{FlushInterval: 10 * time.Millisecond},
+		})
+		defer z.Close()
+
+		z.NoContext().Info("async")
+
+		deadline := time.Now().Add(time.Second)
+		for b.String() == "" {
+			if time.Now().After(deadline) {
+				t.Fatal("expected log flushed on interval")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("should drop entries when buffer is full", func(t *testing.T) {
+		b := newGateWriter()
+
+		z, _ := New(Config{
+			Outputs: []Output{{Writer: b}},
+			Async:   &Async{BufferSize: 1, FlushInterval: time.Millisecond},
+		})
+
+		// wait the flusher blocks on the output
+		z.NoContext().Info("async")
+		<-b.writing
+
+		for i := 0; i < 100; i++ {
+			z.NoContext().Info("async")
+		}
+
+		if z.Dropped() == 0 {
+			t.Fatal("expected dropped entries")
+		}
+
+		close(b.gate)
+		z.Close()
+
+		lines := decodeLines(t, bytes.NewBufferString(b.String()))
+		if uint64(len(lines))+z.Dropped() != 101 {
+			t.Fatal("expected written and dropped entries to sum 101 but got", len(lines), z.Dropped())
+		}
+	})
+
+	t.Run("should block when buffer is full", func(t *testing.T) {
+		b := newGateWriter()
+
+		z, _ := New(Config{
+			Outputs: []Output{{Writer: b}},
+			Async:   &Async{BufferSize: 1, FlushInterval: time.Millisecond, Block: true},
+		})
+
+		// wait the flusher blocks on the output
+		z.NoContext().Info("async")
+		<-b.writing
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 100; i++ {
+				z.NoContext().Info("async")
+			}
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("expected log calls blocked on full buffer")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(b.gate)
+		<-done
+		z.Close()
+
+		if lines := decodeLines(t, bytes.NewBufferString(b.String())); len(lines) != 101 || z.Dropped() != 0 {
+			t.Fatal("expected 101 log lines and no drops but got", len(lines), z.Dropped())
+		}
+	})
+}
@@ -3,60 +3,71 @@ package logecho
 import (
 	"bytes"
 	"io"
+	"os"
 	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
-var paramBuf = &bytes.Buffer{}
-
-func buildParams(c echo.Context) []byte {
-	paramBuf.Reset()
-	keys := c.ParamNames()
-	lastIndex := len(keys)
-
-	paramBuf.WriteRune('{')
-	for i, key := range keys {
-		paramBuf.WriteRune('"')
-		paramBuf.WriteString(key)
-		paramBuf.WriteString(`":"`)
-		paramBuf.WriteString(c.Param(key))
-		paramBuf.WriteRune('"')
-		if i+1 != lastIndex {
-			paramBuf.WriteRune(',')
-		}
+// readBody reads the request body and restores it, so the handler
+// and later reads still get the whole body
+func readBody(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil {
+		return ""
 	}
-	paramBuf.WriteRune('}')
-	return paramBuf.Bytes()
-}
 
-func readBody(c echo.Context) string {
-	bytes, _ := io.ReadAll(c.Request().Body)
-	return string(bytes)
+	body, _ := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return string(body)
 }
 
-func extractCookie(c echo.Context) func(string) string {
-	return func(name string) string {
+// firstCookie returns the value of the first present cookie
+func firstCookie(c echo.Context, names ...string) string {
+	for _, name := range names {
 		cookie, err := c.Cookie(name)
-		if err != nil {
-			return ""
+		if err == nil && cookie.Value != "" {
+			return cookie.Value
 		}
+	}
 
-		return cookie.Value
+	return ""
+}
+
+// firstHeader returns the value of the first non-empty header
+func firstHeader(c echo.Context, headers ...string) string {
+	for _, key := range headers {
+		header := c.Request().Header.Get(key)
+		if header != "" {
+			return header
+		}
 	}
+
+	return ""
 }
 
-func getHeader(c echo.Context) func(headers ...string) string {
-	return func(headers ...string) string {
-		for _, key := range headers {
-			header := c.Request().Header.Get(key)
-			if header != "" {
-				return header
-			}
+// firstParam returns the value of the first non-empty path param
+func firstParam(c echo.Context, names ...string) string {
+	for _, name := range names {
+		if param := c.Param(name); param != "" {
+			return param
 		}
+	}
 
-		return ""
+	return ""
+}
+
+// firstEnv returns the value of the first non-empty env
+func firstEnv(_ echo.Context, envs ...string) string {
+	for _, env := range envs {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
 	}
+
+	return ""
 }
 
 func initLatencyCalc(c echo.Context) {
@@ -71,23 +82,6 @@ func getStartFromCtx(c echo.Context) time.Time {
 	return time.Now()
 }
 
-func calcLatency(c echo.Context) func(string) interface{} {
-	return func(scale string) interface{} {
-		start := getStartFromCtx(c)
-		stop := time.Since(start)
-
-		switch scale {
-		case "s":
-			return stop.Seconds()
-		case "ms":
-			return stop.Milliseconds()
-		case "us":
-			return stop.Microseconds()
-		case "ns":
-			return stop.Nanoseconds()
-		default:
-			return stop.String()
-		}
-
-	}
+func calcLatency(c echo.Context) time.Duration {
+	return time.Since(getStartFromCtx(c))
 }
@@ -1,6 +1,8 @@
 package logecho
 
 import (
+	"strings"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -78,6 +80,23 @@ type Config struct {
 	//
 	// Production (same as IsDevelopment = false) JSON is default
 	Encoding Encoding
+
+	// Outputs sets where logs are written to. Each Output can have
+	// its own minimum level and encoding.
+	//
+	// Default is a single output to Stderr
+	Outputs []Output
+
+	// Async enables asynchronous buffered writing to Outputs.
+	//
+	// Default is nil, who writes synchronously
+	Async *Async
+
+	// Sampling keeps only part of the logs. Logs at Error level and
+	// above are never sampled out.
+	//
+	// Default is nil, who keeps every log
+	Sampling *Sampling
 }
 
 // msgKey returns "message" as default when MessageKey is empty
@@ -129,3 +148,77 @@ func (z Config) getEncoding() Encoding {
 
 	return z.Encoding
 }
+
+// getOutputs returns Stderr output as default when Outputs is empty
+func (z Config) getOutputs() []Output {
+	if len(z.Outputs) == 0 {
+		return defaultOutputs
+	}
+
+	return z.Outputs
+}
+
+// levelKey is the key zap writes the log level on
+const levelKey = "level"
+
+// ConfigError reports every problem found on a Config
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "logecho: invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks Encoding, keys, Level, Outputs and Sampling of config.
+//
+// It returns a *ConfigError with every problem found, or nil
+// when config is valid
+func (z Config) Validate() error {
+	var problems []string
+
+	switch z.getEncoding() {
+	case JSON, Text:
+	default:
+		problems = append(problems, `unknown Encoding "`+string(z.Encoding)+`", expected "json" or "console"`)
+	}
+
+	if z.Level < zapcore.DebugLevel || z.Level > zapcore.FatalLevel {
+		problems = append(problems, "unknown Level "+z.Level.String())
+	}
+
+	keys := map[string]string{levelKey: "level"}
+	for _, key := range []struct{ name, value string }{
+		{"MessageKey", z.msgKey()},
+		{"TimeKey", z.getTimeKey()},
+		{"CallerKey", z.CallerKey},
+	} {
+		if key.value == "" {
+			continue
+		}
+
+		if strings.TrimSpace(key.value) != key.value {
+			problems = append(problems, key.name+` "`+key.value+`" has surrounding spaces`)
+		}
+
+		if other, ok := keys[key.value]; ok {
+			problems = append(problems, key.name+` "`+key.value+`" is already used by `+other)
+			continue
+		}
+		keys[key.value] = key.name
+	}
+
+	for i, output := range z.Outputs {
+		problems = append(problems, output.validate(i)...)
+	}
+
+	if z.Sampling != nil {
+		problems = append(problems, z.Sampling.validate("Sampling")...)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{problems}
+	}
+
+	return nil
+}
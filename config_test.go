@@ -0,0 +1,59 @@
+package logecho
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should build valid configs", func(t *testing.T) {
+		for _, cfg := range []Config{defaultConfig, DevConfig, {Level: zap.ErrorLevel, CallerKey: "caller"}} {
+			z, err := New(cfg)
+			if err != nil {
+				t.Fatal("expected no error but got", err)
+			}
+			if z.Level() != cfg.Level {
+				t.Fatal("expected level", cfg.Level, "but got", z.Level())
+			}
+		}
+	})
+
+	t.Run("should report every config problem", func(t *testing.T) {
+		_, err := New(Config{
+			Encoding:   "yaml",
+			Level:      zapcore.Level(10),
+			MessageKey: "ts",
+			TimeKey:    "ts",
+			CallerKey:  "level",
+		})
+
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatal("expected *ConfigError but got", err)
+		}
+
+		if len(cfgErr.Problems) != 4 {
+			t.Fatal("expected 4 problems but got", cfgErr.Problems)
+		}
+
+		for _, expected := range []string{"Encoding", "Level", "TimeKey", "CallerKey"} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatal("expected error to report", expected, "but got", err)
+			}
+		}
+	})
+
+	t.Run("should panic on NewZapWithConfig with invalid config", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+
+		NewZapWithConfig(Config{Encoding: "yaml"})
+	})
+}
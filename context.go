@@ -0,0 +1,197 @@
+package logecho
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// scopeKey is the context.Context key where the middleware stores
+// the requestScope
+type scopeKey struct{}
+
+// requestScope is what the middleware shares with code who only has
+// the request context.Context: the logger, the correlation ids and
+// the fields bound by With
+type requestScope struct {
+	logger        *Logecho
+	requestID     string
+	transactionID string
+	trace         *traceContext
+
+	// outgoing are the headers who Transport propagates
+	outgoing *outgoingHeaders
+
+	m      sync.RWMutex
+	fields []zapcore.Field
+}
+
+// installScope stores a new requestScope on the request context
+func installScope(c echo.Context, l *Logecho, outgoing *outgoingHeaders) {
+	scope := &requestScope{
+		logger:        l,
+		outgoing:      outgoing,
+		requestID:     getXRequestID(c),
+		transactionID: getTransactionID(c),
+		trace:         getTrace(c),
+	}
+
+	// fields bound by With before the middleware, like by an outer
+	// auth middleware, are kept
+	if fields, ok := c.Get(fieldsKey).([]zapcore.Field); ok {
+		scope.fields = fields
+	}
+
+	req := c.Request()
+	c.SetRequest(req.WithContext(context.WithValue(req.Context(), scopeKey{}, scope)))
+}
+
+// getScope recovers the requestScope from ctx. It returns nil when
+// ctx is not from a request handled by the middleware
+func getScope(ctx context.Context) *requestScope {
+	if ctx == nil {
+		return nil
+	}
+
+	if scope, ok := ctx.Value(scopeKey{}).(*requestScope); ok {
+		return scope
+	}
+
+	return nil
+}
+
+// bind merges fields with the scope bound fields
+func (s *requestScope) bind(fields []zapcore.Field) {
+	s.m.Lock()
+	s.fields = mergeFields(s.fields, fields)
+	s.m.Unlock()
+}
+
+// boundFields returns the scope bound fields
+func (s *requestScope) boundFields() []zapcore.Field {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.fields
+}
+
+// idFields returns the correlation ids as fields
+func (s *requestScope) idFields() []zapcore.Field {
+	fields := make([]zapcore.Field, 0, 4)
+	if s.requestID != "" {
+		fields = append(fields, zap.String("request_id", s.requestID))
+	}
+	if s.transactionID != "" {
+		fields = append(fields, zap.String("transaction_id", s.transactionID))
+	}
+	if s.trace != nil {
+		fields = append(fields, zap.String("trace_id", s.trace.traceID), zap.String("span_id", s.trace.spanID))
+	}
+
+	return fields
+}
+
+// RequestIDFromContext returns the request id of the request handled
+// by the middleware. It is empty when EnableRequestID is disabled or
+// ctx is not from a request handled by the middleware
+func RequestIDFromContext(ctx context.Context) string {
+	if scope := getScope(ctx); scope != nil {
+		return scope.requestID
+	}
+
+	return ""
+}
+
+// TransactionIDFromContext returns the transaction id of the request
+// handled by the middleware. It is empty when ctx is not from a request
+// handled by the middleware
+func TransactionIDFromContext(ctx context.Context) string {
+	if scope := getScope(ctx); scope != nil {
+		return scope.transactionID
+	}
+
+	return ""
+}
+
+// ContextLogger is a logger who needs no echo.Context. It writes
+// through a Logecho with a fixed set of fields.
+//
+// It is safe for concurrent use
+type ContextLogger struct {
+	zl *zap.Logger
+}
+
+// FromContext returns a ContextLogger to ctx.
+//
+// When ctx is the request context of a request handled by the
+// middleware, the ContextLogger writes through the middleware Logecho
+// and carries the request id, the transaction id, the trace id and
+// span id when EnableTraceContext is enabled and the fields bound
+// by With until the call. Otherwise it writes through the Logger
+// singleton without fields.
+//
+// Example:
+//
+//	func (r *repository) Find(ctx context.Context, id string) (*Order, error) {
+//		logecho.FromContext(ctx).Info("finding order", zap.String("order_id", id))
+//		// ...
+//	}
+func FromContext(ctx context.Context) *ContextLogger {
+	scope := getScope(ctx)
+	if scope == nil {
+		return Logger.NoContext()
+	}
+
+	fields := mergeFields(scope.idFields(), scope.boundFields())
+	return &ContextLogger{scope.logger.zl.With(fields...)}
+}
+
+// NoContext returns a ContextLogger who writes through z without
+// any field
+func (z *Logecho) NoContext() *ContextLogger {
+	return &ContextLogger{z.zl}
+}
+
+// With returns a child ContextLogger who carries fields too
+func (l *ContextLogger) With(fields ...zapcore.Field) *ContextLogger {
+	return &ContextLogger{l.zl.With(fields...)}
+}
+
+func (l *ContextLogger) Debug(s string, fields ...zapcore.Field) { l.zl.Debug(s, fields...) }
+func (l *ContextLogger) Info(s string, fields ...zapcore.Field)  { l.zl.Info(s, fields...) }
+func (l *ContextLogger) Warn(s string, fields ...zapcore.Field)  { l.zl.Warn(s, fields...) }
+func (l *ContextLogger) Error(s string, fields ...zapcore.Field) { l.zl.Error(s, fields...) }
+func (l *ContextLogger) Panic(s string, fields ...zapcore.Field) { l.zl.Panic(s, fields...) }
+func (l *ContextLogger) Fatal(s string, fields ...zapcore.Field) { l.zl.Fatal(s, fields...) }
+
+func (l *ContextLogger) Debugf(format string, args ...interface{}) {
+	l.zl.Debug(fmt.Sprintf(format, args...))
+}
+
+func (l *ContextLogger) Infof(format string, args ...interface{}) {
+	l.zl.Info(fmt.Sprintf(format, args...))
+}
+
+func (l *ContextLogger) Warnf(format string, args ...interface{}) {
+	l.zl.Warn(fmt.Sprintf(format, args...))
+}
+
+func (l *ContextLogger) Errorf(format string, args ...interface{}) {
+	l.zl.Error(fmt.Sprintf(format, args...))
+}
+
+// Debug writes s with fields through the Logger singleton
+func Debug(s string, fields ...zapcore.Field) { Logger.zl.Debug(s, fields...) }
+
+// Info writes s with fields through the Logger singleton
+func Info(s string, fields ...zapcore.Field) { Logger.zl.Info(s, fields...) }
+
+// Warn writes s with fields through the Logger singleton
+func Warn(s string, fields ...zapcore.Field) { Logger.zl.Warn(s, fields...) }
+
+// Error writes s with fields through the Logger singleton
+func Error(s string, fields ...zapcore.Field) { Logger.zl.Error(s, fields...) }
@@ -0,0 +1,87 @@
+package logecho
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Run("should carry request ids and bound fields", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		l := newTestLogger(b)
+
+		var requestID, transactionID string
+		repository := func(ctx context.Context) {
+			requestID = RequestIDFromContext(ctx)
+			transactionID = TransactionIDFromContext(ctx)
+			FromContext(ctx).Info("from repository", zap.String("order_id", "10"))
+		}
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{
+			Logger:          l,
+			EnableRequestID: true,
+			Fields:          Fields{"method": Method},
+		}))
+		e.GET("/", func(c echo.Context) error {
+			With(c, zap.String("user_id", "1"))
+			repository(c.Request().Context())
+			return c.NoContent(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("x-transaction-id", "tx-1")
+		rec := serve(e, req)
+
+		if requestID == "" || requestID != rec.Header().Get(echo.HeaderXRequestID) {
+			t.Fatal("expected request id from context as response header but got", requestID)
+		}
+		if transactionID != "tx-1" {
+			t.Fatal("expected transaction id tx-1 but got", transactionID)
+		}
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 {
+			t.Fatal("expected 2 log lines but got", len(lines))
+		}
+
+		line := lines[0]
+		if line["request_id"] != requestID || line["transaction_id"] != "tx-1" {
+			t.Fatal("expected request ids on log but got", line)
+		}
+		if line["user_id"] != "1" || line["order_id"] != "10" {
+			t.Fatal("expected bound and call fields on log but got", line)
+		}
+		if lines[1]["user_id"] != "1" {
+			t.Fatal("expected bound fields on handled request log but got", lines[1])
+		}
+	})
+
+	t.Run("should write through Logger singleton without request", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		useLogger(t, newTestLogger(b))
+
+		FromContext(context.Background()).Warnf("order %d", 10)
+		Info("no context", zap.Int("n", 1))
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 {
+			t.Fatal("expected 2 log lines but got", len(lines))
+		}
+		if lines[0]["msg"] != "order 10" || lines[0]["level"] != "warn" {
+			t.Fatal("expected formatted warn log but got", lines[0])
+		}
+		if _, ok := lines[0]["request_id"]; ok {
+			t.Fatal("expected no request id without request but got", lines[0])
+		}
+		if lines[1]["msg"] != "no context" {
+			t.Fatal("expected plain log but got", lines[1])
+		}
+	})
+}
@@ -3,6 +3,11 @@ package logecho
 import (
 	"bytes"
 	"reflect"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type (
@@ -24,6 +29,10 @@ type (
 		// Header should returns a string. The result will
 		// be reflect.String
 		result reflect.Kind
+
+		// extract builds the typed zapcore.Field to key from
+		// echo.Context and the args the FuncField was built with
+		extract func(c echo.Context, key string, args []string) zapcore.Field
 	}
 
 	// Field is plain valued field on Fields template
@@ -40,6 +49,14 @@ type (
 		// UserAgent as Example has kind as reflect.String
 		// because UserAgent is a string
 		kind reflect.Kind
+
+		// extract builds the typed zapcore.Field to key
+		// straight from echo.Context
+		extract func(c echo.Context, key string) zapcore.Field
+
+		// response marks Response class fields, who are only
+		// known after the handler responds
+		response bool
 	}
 
 	// Fields is a mapping to key->Field. It will be used
@@ -58,39 +75,44 @@ type (
 )
 
 var (
-	RequestURI      = Field{"{{ .RequestURI }}", reflect.String}      // Built-in field to RequestURI - Request class field
-	RequestID       = Field{"{{ .RequestID }}", reflect.String}       // Built-in field to RequestID - Request class field
-	TransactionID   = Field{"{{ .TransactionID }}", reflect.String}   // Built-in field to TransactionID - Request class field
-	RealIP          = Field{"{{ .RealIP }}", reflect.String}          // Built-in field to RealIP - Request class field
-	Host            = Field{"{{ .Host }}", reflect.String}            // Built-in field to Host - Request class field
-	Method          = Field{"{{ .Method }}", reflect.String}          // Built-in field to Method - Request class field
-	Referer         = Field{"{{ .Referer }}", reflect.String}         // Built-in field to Referer - Request class field
-	UserAgent       = Field{"{{ .UserAgent }}", reflect.String}       // Built-in field to UserAgent - Request class field
-	Query           = Field{"{{ .Query }}", reflect.String}           // Built-in field to Query - Request class field
-	Path            = Field{"{{ .Path }}", reflect.String}            // Built-in field to Path - Request class field
-	UrlEncodedQuery = Field{"{{ .UrlEncodedQuery }}", reflect.String} // Built-in field to UrlEncodedQuery - Request class field
-	BytesIn         = Field{"{{ .BytesIn }}", reflect.String}         // Built-in field to BytesIn - Request class field
-	Body            = Field{"{{ .Body }}", reflect.String}            // Built-in field to Body - Request class field
-	Params          = Field{"{{ .Params }}", reflect.String}          // Built-in field to Params - Request class field
-
-	Status   = Field{"{{ .Status }}", reflect.Int}     // Built-in field to Status - Response class field. It can be empty or with default value
-	BytesOut = Field{"{{ .BytesOut }}", reflect.Int64} // Built-in field to BytesOut - Response class field. It can be empty or with default value
-
-	LatencyInMicroS = Field{"{{ Latency \"us\" }}", reflect.Int}        // Built-in field to Latency in microseconds
-	LatencyInNs     = Field{"{{ Latency \"ns\" }}", reflect.Int}        // Built-in field to Latency in nanoseconds
-	LatencyInMs     = Field{"{{ Latency \"ms\" }}", reflect.Int}        // Built-in field to Latency in milliseconds
-	LatencyInSec    = Field{"{{ Latency \"s\" }}", reflect.Int}         // Built-in field to Latency in seconds
-	LatencyString   = Field{"{{ Latency \"string\" }}", reflect.String} // Built-in field to Latency in string format
+	RequestURI      = stringField("{{ .RequestURI }}", requestURI)              // Built-in field to RequestURI - Request class field
+	RequestID       = stringField("{{ .RequestID }}", getXRequestID)            // Built-in field to RequestID - Request class field
+	TransactionID   = stringField("{{ .TransactionID }}", getTransactionID)     // Built-in field to TransactionID - Request class field
+	TraceID         = stringField("{{ .TraceID }}", getTraceID)                 // Built-in field to TraceID - Request class field. It needs EnableTraceContext
+	SpanID          = stringField("{{ .SpanID }}", getSpanID)                   // Built-in field to SpanID - Request class field. It needs EnableTraceContext
+	ParentSpanID    = stringField("{{ .ParentSpanID }}", getParentSpanID)       // Built-in field to ParentSpanID - Request class field. It is empty to new traces
+	TraceSampled    = optionalBoolField("{{ .TraceSampled }}", getTraceSampled) // Built-in field to TraceSampled - Request class field. It is skipped when the caller deferred sampling
+	RealIP          = stringField("{{ .RealIP }}", realIP)                      // Built-in field to RealIP - Request class field
+	Host            = stringField("{{ .Host }}", host)                          // Built-in field to Host - Request class field
+	Method          = stringField("{{ .Method }}", method)                      // Built-in field to Method - Request class field
+	Referer         = stringField("{{ .Referer }}", referer)                    // Built-in field to Referer - Request class field
+	UserAgent       = stringField("{{ .UserAgent }}", userAgent)                // Built-in field to UserAgent - Request class field
+	Query           = objectField("{{ .Query }}", query)                        // Built-in field to Query - Request class field. It is logged as an object of key to values
+	Path            = stringField("{{ .Path }}", path)                          // Built-in field to Path - Request class field
+	UrlEncodedQuery = stringField("{{ .UrlEncodedQuery }}", urlEncodedQuery)    // Built-in field to UrlEncodedQuery - Request class field
+	BytesIn         = stringField("{{ .BytesIn }}", bytesIn)                    // Built-in field to BytesIn - Request class field
+	Body            = stringField("{{ .Body }}", readBody)                      // Built-in field to Body - Request class field
+	Params          = objectField("{{ .Params }}", params)                      // Built-in field to Params - Request class field. It is logged as an object of name to value
+
+	Status   = responseClass(intField("{{ .Status }}", status))       // Built-in field to Status - Response class field. It can be empty or with default value
+	BytesOut = responseClass(int64Field("{{ .BytesOut }}", bytesOut)) // Built-in field to BytesOut - Response class field. It can be empty or with default value
+
+	LatencyInMicroS = latencyField("us", reflect.Int64)       // Built-in field to Latency in microseconds
+	LatencyInNs     = latencyField("ns", reflect.Int64)       // Built-in field to Latency in nanoseconds
+	LatencyInMs     = latencyField("ms", reflect.Int64)       // Built-in field to Latency in milliseconds
+	LatencyInSec    = latencyField("s", reflect.Float64)      // Built-in field to Latency in seconds, with fraction
+	LatencyString   = latencyField("string", reflect.String)  // Built-in field to Latency in string format
+	LatencyDuration = latencyField("duration", reflect.Int64) // Built-in field to Latency as duration. It is formatted by the logger EncodeDuration
 )
 
 var (
-	ParamField      = FuncField{"Param", reflect.String}        // Built-in FuncField to Param function
-	HeaderField     = FuncField{"Header", reflect.String}       // Built-in FuncField to Header function
-	CookieField     = FuncField{"Cookie", reflect.String}       // Built-in FuncField to Cookie function
-	LatencyField    = FuncField{"Latency", reflect.String}      // Built-in FuncField to Latency function
-	GetenvField     = FuncField{"Getenv", reflect.String}       // Built-in FuncField to Getenv function
-	RunningReqField = FuncField{"RunningReqField", reflect.Int} // Built-in FuncField to RunningReq function
-	ConcurrentField = FuncField{"Concurrent", reflect.Int}      // Built-in FuncField to Concurrent function
+	ParamField      = FuncField{"Param", reflect.String, stringFuncField(firstParam)}           // Built-in FuncField to Param function
+	HeaderField     = FuncField{"Header", reflect.String, stringFuncField(firstHeader)}         // Built-in FuncField to Header function
+	CookieField     = FuncField{"Cookie", reflect.String, stringFuncField(firstCookie)}         // Built-in FuncField to Cookie function
+	LatencyField    = FuncField{"Latency", reflect.String, latencyFuncField}                    // Built-in FuncField to Latency function
+	GetenvField     = FuncField{"Getenv", reflect.String, stringFuncField(firstEnv)}            // Built-in FuncField to Getenv function
+	RunningReqField = FuncField{"RunningReqField", reflect.Int, uint64FuncField(CurrentCount)}  // Built-in FuncField to RunningReq function
+	ConcurrentField = FuncField{"Concurrent", reflect.Int, uint64FuncField(TransactionCounter)} // Built-in FuncField to Concurrent function
 )
 
 var (
@@ -163,15 +185,16 @@ func getenv(env string) Field {
 //
 // Example:
 //
-//	customField := FuncField{"Custom",reflect.String}
+//	customField := FuncField{"Custom", reflect.String, extract}
 //	f := FuncFieldWithArgs(customField, "arg1", "arg2")
 //
-// Will build the following template:
+// Will build the following template, where args are quoted
+// and escaped as Go string literals:
 //
-//	"{{ Custom \"arg1\" \"arg2\" }}"
+//	"{{ .Custom \"arg1\" \"arg2\" }}"
 func FuncFieldWithArgs(field FuncField, args ...string) Field {
 	b := new(bytes.Buffer)
-	b.WriteString("{{")
+	b.WriteString("{{.")
 	b.WriteString(field.name)
 	for _, arg := range args {
 		if arg == "" {
@@ -179,13 +202,23 @@ func FuncFieldWithArgs(field FuncField, args ...string) Field {
 		}
 
 		b.WriteRune(' ')
-		b.WriteString(`"`)
-		b.WriteString(arg)
-		b.WriteString(`"`)
+		b.WriteString(strconv.Quote(arg))
 	}
 	b.WriteString("}}")
 
-	return Field{b.String(), field.result}
+	return Field{tpl: b.String(), kind: field.result, extract: bindArgs(field, args)}
+}
+
+// bindArgs binds args to FuncField extract, so the built Field
+// extracts with the args it was built with
+func bindArgs(field FuncField, args []string) func(echo.Context, string) zapcore.Field {
+	if field.extract == nil {
+		return nil
+	}
+
+	return func(c echo.Context, key string) zapcore.Field {
+		return field.extract(c, key, args)
+	}
 }
 
 // Tpl will return built tpl to the Field
@@ -194,7 +227,7 @@ func FuncFieldWithArgs(field FuncField, args ...string) Field {
 //
 // Or if a FuncField result
 //
-//	"{{ Field \"arg\" }}"
+//	"{{ .Field \"arg\" }}"
 func (f Field) Tpl() string {
 	return f.tpl
 }
@@ -203,3 +236,13 @@ func (f Field) Tpl() string {
 func (f Field) Type() reflect.Kind {
 	return f.kind
 }
+
+// zapField extracts the field value from echo.Context as a typed
+// zapcore.Field to key. A Field without extractor is skipped
+func (f Field) zapField(c echo.Context, key string) zapcore.Field {
+	if f.extract == nil {
+		return zap.Skip()
+	}
+
+	return f.extract(c, key)
+}
@@ -0,0 +1,14 @@
+package logecho
+
+import "testing"
+
+func TestFuncFieldWithArgs(t *testing.T) {
+	t.Run("should quote args on template", func(t *testing.T) {
+		f := FuncFieldWithArgs(HeaderField, `x" }}{{ .Body`, `back\slash`)
+		expected := `{{.Header "x\" }}{{ .Body" "back\\slash"}}`
+
+		if f.Tpl() != expected {
+			t.Fatal("expected tpl as", expected, "but is", f.Tpl())
+		}
+	})
+}
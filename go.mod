@@ -5,13 +5,10 @@ go 1.19
 require (
 	github.com/google/uuid v1.3.0
 	github.com/labstack/echo/v4 v4.10.0
-	github.com/pkg/profile v1.7.0
 	go.uber.org/zap v1.24.0
 )
 
 require (
-	github.com/felixge/fgprof v0.9.3 // indirect
-	github.com/google/pprof v0.0.0-20211214055906-6f57359322fd // indirect
 	github.com/labstack/gommon v0.4.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.16 // indirect
@@ -0,0 +1,125 @@
+package logecho
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OutcomeLog sets level and message of the "handled request" log to
+// a request outcome
+type OutcomeLog struct {
+	// Level is the log level to the outcome. Default is INFO
+	Level zapcore.Level
+
+	// Message is the log message to the outcome.
+	//
+	// Default is HandledLog Message
+	Message string
+}
+
+// HandledLog sets the "handled request" log of the middleware by
+// the request outcome.
+//
+// Outcomes are checked in order: ServerError, ClientError, Slow and
+// Success. A nil outcome is not checked.
+//
+// Example:
+//
+//	logecho.HandledLog{
+//		ServerError:   &logecho.OutcomeLog{Level: zap.ErrorLevel, Message: "request failed"},
+//		ClientError:   &logecho.OutcomeLog{Level: zap.WarnLevel},
+//		Slow:          &logecho.OutcomeLog{Level: zap.WarnLevel},
+//		SlowThreshold: time.Second,
+//	}
+type HandledLog struct {
+	// Message is the log message to every outcome who has no
+	// message.
+	//
+	// Default is "handled request"
+	Message string
+
+	// ServerError is the log to requests with status 5xx
+	ServerError *OutcomeLog
+
+	// ClientError is the log to requests with status 4xx
+	ClientError *OutcomeLog
+
+	// Slow is the log to requests who took more than SlowThreshold
+	Slow *OutcomeLog
+
+	// SlowThreshold is the latency who marks a request as slow.
+	// Slow requests get the SlowKey field as true, whatever is the
+	// outcome.
+	//
+	// Default is 0, who never marks requests as slow
+	SlowThreshold time.Duration
+
+	// SlowKey is the key of the slow marker field.
+	//
+	// Default is "slow"
+	SlowKey string
+
+	// Success is the log to requests who match no other outcome
+	Success OutcomeLog
+}
+
+// DefaultHandledLog logs 5xx as ERROR, 4xx and requests slower than
+// a second as WARN and everything else as INFO
+var DefaultHandledLog = HandledLog{
+	ServerError:   &OutcomeLog{Level: zap.ErrorLevel},
+	ClientError:   &OutcomeLog{Level: zap.WarnLevel},
+	Slow:          &OutcomeLog{Level: zap.WarnLevel},
+	SlowThreshold: time.Second,
+}
+
+// getMessage returns "handled request" as default when Message is empty
+func (h HandledLog) getMessage() string {
+	if h.Message == "" {
+		return "handled request"
+	}
+
+	return h.Message
+}
+
+// getSlowKey returns "slow" as default when SlowKey is empty
+func (h HandledLog) getSlowKey() string {
+	if h.SlowKey == "" {
+		return "slow"
+	}
+
+	return h.SlowKey
+}
+
+// outcome returns the OutcomeLog to the request status and latency
+func (h HandledLog) outcome(status int, slow bool) OutcomeLog {
+	switch {
+	case h.ServerError != nil && status >= 500:
+		return *h.ServerError
+	case h.ClientError != nil && status >= 400 && status < 500:
+		return *h.ClientError
+	case h.Slow != nil && slow:
+		return *h.Slow
+	default:
+		return h.Success
+	}
+}
+
+// write logs the handled request through l by its outcome
+func (h HandledLog) write(l *Logecho, c echo.Context, latency time.Duration, fields ...zapcore.Field) {
+	slow := h.SlowThreshold > 0 && latency > h.SlowThreshold
+	outcome := h.outcome(c.Response().Status, slow)
+
+	msg := outcome.Message
+	if msg == "" {
+		msg = h.getMessage()
+	}
+
+	if slow {
+		fields = append(fields, zap.Bool(h.getSlowKey(), true))
+	}
+
+	l.Log(c, outcome.Level, msg, fields...)
+}
@@ -0,0 +1,92 @@
+package logecho
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestHandledLog(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger: l,
+		Fields: Fields{"path": Path},
+		HandledLog: &HandledLog{
+			Message:       "done",
+			ServerError:   &OutcomeLog{Level: zap.ErrorLevel, Message: "request failed"},
+			ClientError:   &OutcomeLog{Level: zap.WarnLevel},
+			Slow:          &OutcomeLog{Level: zap.WarnLevel, Message: "slow request"},
+			SlowThreshold: 20 * time.Millisecond,
+			Success:       OutcomeLog{Level: zap.DebugLevel},
+		},
+	}))
+	e.GET("/ok", okHandler)
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadGateway)
+	})
+	e.GET("/slow", func(c echo.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		return okHandler(c)
+	})
+	e.GET("/slow-fail", func(c echo.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	expected := []struct {
+		target, level, msg string
+		slow               bool
+	}{
+		{"/ok", "debug", "done", false},
+		{"/missing", "warn", "done", false},
+		{"/fail", "error", "request failed", false},
+		{"/slow", "warn", "slow request", true},
+		{"/slow-fail", "error", "request failed", true},
+	}
+
+	for _, e2 := range expected {
+		serve(e, httptest.NewRequest(http.MethodGet, e2.target, nil))
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != len(expected) {
+		t.Fatal("expected", len(expected), "log lines but got", len(lines))
+	}
+
+	for i, e := range expected {
+		line := lines[i]
+		if line["path"] != e.target || line["level"] != e.level || line["msg"] != e.msg {
+			t.Fatal("expected", e.level, e.msg, "to", e.target, "but got", line)
+		}
+
+		if _, slow := line["slow"]; slow != e.slow {
+			t.Fatal("expected slow marker", e.slow, "to", e.target, "but got", line)
+		}
+	}
+}
+
+func TestHandledLogDefault(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: l}))
+	e.GET("/fail", func(c echo.Context) error {
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || lines[0]["level"] != "info" || lines[0]["msg"] != "handled request" {
+		t.Fatal("expected info handled request without HandledLog but got", lines)
+	}
+}
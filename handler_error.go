@@ -0,0 +1,113 @@
+package logecho
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorKeys sets the keys of the handler error fields on the
+// "handled request" log. Empty keys use the defaults
+type ErrorKeys struct {
+	// Error is the key of the error message. Default is "error"
+	Error string
+
+	// Code is the key of the *echo.HTTPError code. Default is
+	// "error_code"
+	Code string
+
+	// Message is the key of the *echo.HTTPError message. Default is
+	// "error_message"
+	Message string
+
+	// Internal is the key of the *echo.HTTPError internal error.
+	// Default is "error_internal"
+	Internal string
+
+	// Chain is the key of the errors.Unwrap chain messages. Default
+	// is "error_chain"
+	Chain string
+
+	// Stack is the key of the error stack trace. Default is
+	// "error_stack"
+	Stack string
+}
+
+// withDefaults fills the empty keys with defaults
+func (k ErrorKeys) withDefaults() ErrorKeys {
+	defaults := map[*string]string{
+		&k.Error:    "error",
+		&k.Code:     "error_code",
+		&k.Message:  "error_message",
+		&k.Internal: "error_internal",
+		&k.Chain:    "error_chain",
+		&k.Stack:    "error_stack",
+	}
+
+	for key, value := range defaults {
+		if *key == "" {
+			*key = value
+		}
+	}
+
+	return k
+}
+
+// fields builds the error fields to err. It returns nil to nil err.
+//
+// The stack trace is taken from the first error in the chain who
+// has a StackTrace method, like the errors of github.com/pkg/errors
+func (k ErrorKeys) fields(err error) []zapcore.Field {
+	if err == nil {
+		return nil
+	}
+
+	fields := []zapcore.Field{zap.String(k.Error, err.Error())}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		fields = append(fields,
+			zap.Int(k.Code, he.Code),
+			zap.String(k.Message, fmt.Sprint(he.Message)),
+		)
+
+		if he.Internal != nil {
+			fields = append(fields, zap.String(k.Internal, he.Internal.Error()))
+		}
+	}
+
+	var chain []string
+	stack := ""
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, e.Error())
+
+		if stack == "" {
+			stack = stackTrace(e)
+		}
+	}
+
+	if len(chain) > 1 {
+		fields = append(fields, zap.Strings(k.Chain, chain))
+	}
+
+	if stack != "" {
+		fields = append(fields, zap.String(k.Stack, stack))
+	}
+
+	return fields
+}
+
+// stackTrace formats the result of err StackTrace method with %+v.
+// It returns empty when err has no StackTrace method
+func stackTrace(err error) string {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+
+	return fmt.Sprintf("%+v", method.Call(nil)[0].Interface())
+}
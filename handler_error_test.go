@@ -0,0 +1,84 @@
+package logecho
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// stackError is an error who carries a stack trace
+type stackError struct{ msg string }
+
+func (e stackError) Error() string      { return e.msg }
+func (e stackError) StackTrace() string { return "main.handler\n\tmain.go:10" }
+
+func TestHandlerErrorFields(t *testing.T) {
+	t.Run("should log HTTPError details", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		l := newTestLogger(b)
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: l, Fields: Fields{"status": Status}}))
+		e.GET("/", func(c echo.Context) error {
+			internal := fmt.Errorf("dial upstream: %w", stackError{"connection refused"})
+			return echo.NewHTTPError(http.StatusBadGateway, "upstream unavailable").SetInternal(internal)
+		})
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		lines := decodeLines(t, b)
+		if len(lines) != 1 {
+			t.Fatal("expected 1 log line but got", len(lines))
+		}
+
+		line := lines[0]
+		if line["error_code"] != jsonNumber(http.StatusBadGateway) || line["error_message"] != "upstream unavailable" {
+			t.Fatal("expected HTTPError code and message but got", line)
+		}
+		if line["error_internal"] != "dial upstream: connection refused" {
+			t.Fatal("expected internal error but got", line["error_internal"])
+		}
+		if chain, ok := line["error_chain"].([]interface{}); !ok || len(chain) != 3 || chain[2] != "connection refused" {
+			t.Fatal("expected error chain but got", line["error_chain"])
+		}
+		if line["error_stack"] != "main.handler\n\tmain.go:10" {
+			t.Fatal("expected stack trace but got", line["error_stack"])
+		}
+		if line["error"] == nil {
+			t.Fatal("expected error message but got", line)
+		}
+	})
+
+	t.Run("should use custom keys", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		l := newTestLogger(b)
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: l, ErrorKeys: ErrorKeys{Error: "err.msg"}}))
+		e.GET("/", func(c echo.Context) error {
+			return errors.New("boom")
+		})
+		e.GET("/ok", okHandler)
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+		serve(e, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+		lines := decodeLines(t, b)
+		if lines[0]["err.msg"] != "boom" {
+			t.Fatal("expected error on custom key but got", lines[0])
+		}
+		for _, key := range []string{"error_code", "error_chain", "error_stack"} {
+			if _, ok := lines[0][key]; ok {
+				t.Fatal("expected no", key, "to plain error but got", lines[0])
+			}
+		}
+		if _, ok := lines[1]["err.msg"]; ok {
+			t.Fatal("expected no error fields without error but got", lines[1])
+		}
+	})
+}
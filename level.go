@@ -0,0 +1,132 @@
+package logecho
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRevert holds the pending revert of a temporary level
+type levelRevert struct {
+	m     sync.Mutex
+	timer *time.Timer
+	// base is the level to revert to. It is the level before the
+	// first temporary change while a revert is pending
+	base zapcore.Level
+}
+
+// Level returns the current minimum level of logs who appear
+func (z *Logecho) Level() zapcore.Level {
+	return z.level.Level()
+}
+
+// SetLevel changes the minimum level of logs who appear at runtime.
+//
+// It cancels any pending revert from SetLevelFor
+func (z *Logecho) SetLevel(level zapcore.Level) {
+	z.SetLevelFor(level, 0)
+}
+
+// SetLevelFor changes the minimum level of logs who appear and
+// reverts it to the previous level after d.
+//
+// When d is zero or negative the level is not reverted. Calling it
+// again while a revert is pending replaces the pending revert, but
+// keeps the level to revert to
+func (z *Logecho) SetLevelFor(level zapcore.Level, d time.Duration) {
+	r := z.revert
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.timer == nil || !r.timer.Stop() {
+		r.base = z.level.Level()
+	}
+	r.timer = nil
+
+	z.level.SetLevel(level)
+
+	if d <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		r.m.Lock()
+		defer r.m.Unlock()
+
+		// a later call already replaced that revert
+		if r.timer != timer {
+			return
+		}
+
+		z.level.SetLevel(r.base)
+		r.timer = nil
+	})
+	r.timer = timer
+}
+
+// levelPayload is the JSON body of LevelHandler
+type levelPayload struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// LevelHandler is an echo.HandlerFunc to read and change the level of
+// a running Logecho.
+//
+// GET responds the current level:
+//
+//	{"level":"info"}
+//
+// PUT changes the level. The optional duration reverts the level
+// after it elapses, in time.ParseDuration format:
+//
+//	{"level":"debug","duration":"5m"}
+//
+// Example:
+//
+//	admin.GET("/log/level", logecho.LevelHandler(logecho.Logger))
+//	admin.PUT("/log/level", logecho.LevelHandler(logecho.Logger))
+func LevelHandler(z *Logecho) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if err := putLevel(z, c); err != nil {
+				return err
+			}
+		default:
+			return echo.ErrMethodNotAllowed
+		}
+
+		return c.JSON(http.StatusOK, levelPayload{Level: z.Level().String()})
+	}
+}
+
+// putLevel reads the levelPayload from request body and applies it
+func putLevel(z *Logecho, c echo.Context) error {
+	var payload levelPayload
+	if err := json.NewDecoder(c.Request().Body).Decode(&payload); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid level payload: "+err.Error())
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(payload.Level)); err != nil || payload.Level == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "unknown level: "+payload.Level)
+	}
+
+	var d time.Duration
+	if payload.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(payload.Duration); err != nil || d < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid duration: "+payload.Duration)
+		}
+	}
+
+	z.SetLevelFor(level, d)
+	return nil
+}
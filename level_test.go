@@ -0,0 +1,88 @@
+package logecho
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestLevelHandler(t *testing.T) {
+	l := newTestLogger(io.Discard)
+	l.SetLevel(zap.InfoLevel)
+
+	e := echo.New()
+	e.GET("/level", LevelHandler(l))
+	e.PUT("/level", LevelHandler(l))
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		return serve(e, req)
+	}
+
+	t.Run("should read level", func(t *testing.T) {
+		rec := serve(e, httptest.NewRequest(http.MethodGet, "/level", nil))
+
+		var payload levelPayload
+		json.NewDecoder(rec.Body).Decode(&payload)
+		if rec.Code != http.StatusOK || payload.Level != "info" {
+			t.Fatal("expected level info but got", rec.Code, payload.Level)
+		}
+	})
+
+	t.Run("should change level", func(t *testing.T) {
+		rec := put(`{"level":"error"}`)
+		if rec.Code != http.StatusOK || l.Level() != zap.ErrorLevel {
+			t.Fatal("expected level error but got", rec.Code, l.Level())
+		}
+		if l.zl.Core().Enabled(zap.WarnLevel) {
+			t.Fatal("expected warn logs disabled")
+		}
+	})
+
+	t.Run("should reject invalid payloads", func(t *testing.T) {
+		for _, body := range []string{`{"level":"loud"}`, `{}`, `{"level":"debug","duration":"soon"}`, `level`} {
+			if rec := put(body); rec.Code != http.StatusBadRequest {
+				t.Fatal("expected bad request to", body, "but got", rec.Code)
+			}
+		}
+		if l.Level() != zap.ErrorLevel {
+			t.Fatal("expected level unchanged but got", l.Level())
+		}
+	})
+
+	t.Run("should revert level after duration", func(t *testing.T) {
+		l.SetLevel(zap.InfoLevel)
+
+		put(`{"level":"debug","duration":"50ms"}`)
+		put(`{"level":"warn","duration":"50ms"}`)
+		if l.Level() != zap.WarnLevel {
+			t.Fatal("expected level warn but got", l.Level())
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for l.Level() != zap.InfoLevel {
+			if time.Now().After(deadline) {
+				t.Fatal("expected level reverted to info but got", l.Level())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("should cancel revert on SetLevel", func(t *testing.T) {
+		l.SetLevelFor(zap.DebugLevel, 20*time.Millisecond)
+		l.SetLevel(zap.ErrorLevel)
+
+		time.Sleep(50 * time.Millisecond)
+		if l.Level() != zap.ErrorLevel {
+			t.Fatal("expected level error but got", l.Level())
+		}
+	})
+}
@@ -7,9 +7,10 @@
 package logecho
 
 import (
-	"sync"
+	"fmt"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Encoding is the string to represents the accepted
@@ -29,23 +30,56 @@ const (
 	Text Encoding = "console"
 )
 
-// Logecho wrapps the zap.Logger in to a logger who has
-// mutex.
+// Logecho wrapps the zap.Logger in to a logger who reads
+// fields from echo.Context.
 //
-// That struct will manage read-write locks on the instance.
-// The original logger is from package go.uber.org/zap
+// It is safe for concurrent use without any lock on the log calls,
+// as the zap.Logger is. The original logger is from package
+// go.uber.org/zap
 //
 // More about zap logger:
 //
 // https://pkg.go.dev/go.uber.org/zap
 type Logecho struct {
 	zl *zap.Logger
-	m  *sync.RWMutex
+
+	// level is the level shared by the zap.Logger cores, so it
+	// can be changed at runtime
+	level  zap.AtomicLevel
+	revert *levelRevert
+
+	// closers release the files opened to Config Outputs and
+	// drain Async buffers. They run in reverse order on Close
+	closers []func()
+
+	// dropped counts entries dropped by full Async buffers
+	dropped uint64
 }
 
-// NewZapWithConfig enables custom configuration to instantiate
-// a new ZapLog
-func NewZapWithConfig(config Config) *Logecho {
+// newLogecho wraps zl who writes logs from level
+func newLogecho(zl *zap.Logger, level zap.AtomicLevel) *Logecho {
+	return &Logecho{
+		zl:     zl,
+		level:  level,
+		revert: &levelRevert{},
+	}
+}
+
+// New instantiates a new Logecho with config.
+//
+// It validates config before building the logger. All problems
+// found are reported in a single *ConfigError, so applications can
+// fail gracefully or fall back to defaults:
+//
+//	log, err := logecho.New(cfg)
+//	if err != nil {
+//		log = logecho.NewZap()
+//	}
+func New(config Config) (*Logecho, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	initConfig := zap.NewProductionConfig()
 	if config.IsDevelopment {
 		initConfig = zap.NewDevelopmentConfig()
@@ -55,8 +89,6 @@ func NewZapWithConfig(config Config) *Logecho {
 		initConfig.EncoderConfig.EncodeCaller = nil
 	}
 
-	initConfig.Sampling = nil // disable sampling
-
 	initConfig.EncoderConfig.MessageKey = config.msgKey()
 	initConfig.EncoderConfig.CallerKey = config.CallerKey
 	initConfig.EncoderConfig.TimeKey = config.getTimeKey()
@@ -65,12 +97,99 @@ func NewZapWithConfig(config Config) *Logecho {
 	initConfig.Level = zap.NewAtomicLevelAt(config.Level)
 	initConfig.Encoding = string(config.getEncoding())
 
-	zapLog := &Logecho{
-		zl: zap.Must(initConfig.Build()),
-		m:  &sync.RWMutex{},
+	z := newLogecho(nil, initConfig.Level)
+
+	cores := make([]zapcore.Core, 0, len(config.getOutputs()))
+	for i, output := range config.getOutputs() {
+		core, err := output.core(initConfig.EncoderConfig, config.getEncoding(), initConfig.Level, z.sinkWrapper(config))
+		if err != nil {
+			z.Close()
+			return nil, fmt.Errorf("logecho: open Outputs[%d]: %w", i, err)
+		}
+
+		cores = append(cores, core)
+	}
+
+	errSink, _, err := zap.Open(initConfig.ErrorOutputPaths...)
+	if err != nil {
+		z.Close()
+		return nil, fmt.Errorf("logecho: open error output: %w", err)
+	}
+
+	opts := []zap.Option{zap.ErrorOutput(errSink)}
+	if config.CallerKey != "" {
+		opts = append(opts, zap.AddCaller())
+	}
+
+	stackLevel := zap.ErrorLevel
+	if config.IsDevelopment {
+		stackLevel = zap.WarnLevel
+		opts = append(opts, zap.Development())
+	}
+	opts = append(opts, zap.AddStacktrace(stackLevel))
+
+	core := zapcore.NewTee(cores...)
+	if config.Sampling != nil {
+		core = &samplingCore{core, newSampler(*config.Sampling)}
+	}
+
+	z.zl = zap.New(core, opts...)
+
+	return z, nil
+}
+
+// sinkWrapper opens the output sinks. It registers their close func
+// and wraps them into an asyncWriter when config has Async
+func (z *Logecho) sinkWrapper(config Config) func(o Output) (zapcore.WriteSyncer, error) {
+	return func(o Output) (zapcore.WriteSyncer, error) {
+		ws, closeSink, err := o.sink()
+		if err != nil {
+			return nil, err
+		}
+		z.closers = append(z.closers, closeSink)
+
+		if config.Async == nil {
+			return ws, nil
+		}
+
+		async := newAsyncWriter(ws, *config.Async, &z.dropped)
+		z.closers = append(z.closers, func() { async.Close() })
+
+		return async, nil
+	}
+}
+
+// Close flushes the buffered logs and releases the outputs. It is
+// meant to be called on application shutdown, after echo Shutdown,
+// when no more logs are written.
+//
+// With Async it drains the buffers before the outputs are closed
+func (z *Logecho) Close() error {
+	var err error
+	if z.zl != nil {
+		err = z.zl.Sync()
+	}
+
+	closers := z.closers
+	z.closers = nil
+	for i := len(closers) - 1; i >= 0; i-- {
+		closers[i]()
 	}
 
-	return zapLog
+	return err
+}
+
+// NewZapWithConfig enables custom configuration to instantiate
+// a new ZapLog.
+//
+// It panics when config is invalid. Use New to handle the error
+func NewZapWithConfig(config Config) *Logecho {
+	z, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return z
 }
 
 // NewZap instantiate a ZapLog with default configs
@@ -80,3 +199,16 @@ func NewZap() *Logecho {
 
 // Logger is a singleton to a ZapLog instance
 var Logger = NewZap()
+
+// SetLogger replaces the Logger singleton by l. Middlewares without
+// a MiddlewareConfig.Logger start to write through l too.
+//
+// It should be called on application set up, before it serves
+// requests. A nil l is ignored
+func SetLogger(l *Logecho) {
+	if l == nil {
+		return
+	}
+
+	Logger = l
+}
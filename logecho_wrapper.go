@@ -1,44 +1,164 @@
 package logecho
 
 import (
+	"fmt"
+
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func (z *Logecho) acquireContext(c echo.Context, call func(f ...zapcore.Field)) {
-	z.m.Lock()
-	call(getFields(readContext(c))...)
-	z.m.Unlock()
+// acquireContext checks s at level and, only when it is logged, reads
+// the template fields and the fields bound by With from echo.Context,
+// merges them with fields and writes the entry.
+//
+// Fields passed to the log call override bound and template fields
+// with the same key
+func (z *Logecho) acquireContext(c echo.Context, level zapcore.Level, s string, fields []zapcore.Field) {
+	if ce := z.zl.Check(level, s); ce != nil {
+		ce.Write(mergeFields(mergeFields(readContext(c), getBoundFields(c)), fields)...)
+	}
+}
+
+// mergeFields appends overrides to base, dropping base fields who
+// has the same key of any override
+func mergeFields(base, overrides []zapcore.Field) []zapcore.Field {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make([]zapcore.Field, 0, len(base)+len(overrides))
+	for _, field := range base {
+		if !hasKey(overrides, field.Key) {
+			merged = append(merged, field)
+		}
+	}
+
+	return append(merged, overrides...)
+}
+
+// hasKey checks if any of fields has key
+func hasKey(fields []zapcore.Field, key string) bool {
+	for _, field := range fields {
+		if field.Key == key {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (z *Logecho) Print(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Debug(s, f...) })
+func (z *Logecho) Print(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.DebugLevel, s, fields)
 }
 
 func (z *Logecho) Printf(format string, i ...interface{}) {
 	z.zl.Sugar().Debugf(format, i...)
 }
 
-func (z *Logecho) Debug(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Debug(s, f...) })
+func (z *Logecho) Debug(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.DebugLevel, s, fields)
+}
+
+func (z *Logecho) Info(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.InfoLevel, s, fields)
+}
+
+func (z *Logecho) Warn(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.WarnLevel, s, fields)
+}
+
+func (z *Logecho) Error(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.ErrorLevel, s, fields)
+}
+
+func (z *Logecho) Panic(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.PanicLevel, s, fields)
 }
 
-func (z *Logecho) Info(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Info(s, f...) })
+func (z *Logecho) Fatal(c echo.Context, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, zap.FatalLevel, s, fields)
 }
 
-func (z *Logecho) Warn(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Warn(s, f...) })
+// Log writes s at level. It is useful when the level is only known
+// at runtime
+func (z *Logecho) Log(c echo.Context, level zapcore.Level, s string, fields ...zapcore.Field) {
+	z.acquireContext(c, level, s, fields)
 }
 
-func (z *Logecho) Error(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Error(s, f...) })
+func (z *Logecho) Debugf(c echo.Context, format string, args ...interface{}) {
+	if z.zl.Core().Enabled(zap.DebugLevel) {
+		z.Debug(c, fmt.Sprintf(format, args...))
+	}
 }
 
-func (z *Logecho) Panic(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Panic(s, f...) })
+func (z *Logecho) Infof(c echo.Context, format string, args ...interface{}) {
+	if z.zl.Core().Enabled(zap.InfoLevel) {
+		z.Info(c, fmt.Sprintf(format, args...))
+	}
 }
 
-func (z *Logecho) Fatal(c echo.Context, s string) {
-	z.acquireContext(c, func(f ...zapcore.Field) { z.zl.Fatal(s, f...) })
+func (z *Logecho) Warnf(c echo.Context, format string, args ...interface{}) {
+	if z.zl.Core().Enabled(zap.WarnLevel) {
+		z.Warn(c, fmt.Sprintf(format, args...))
+	}
+}
+
+func (z *Logecho) Errorf(c echo.Context, format string, args ...interface{}) {
+	if z.zl.Core().Enabled(zap.ErrorLevel) {
+		z.Error(c, fmt.Sprintf(format, args...))
+	}
+}
+
+func (z *Logecho) Debugw(c echo.Context, s string, keysAndValues ...interface{}) {
+	if z.zl.Core().Enabled(zap.DebugLevel) {
+		z.Debug(c, s, keyValueFields(keysAndValues)...)
+	}
+}
+
+func (z *Logecho) Infow(c echo.Context, s string, keysAndValues ...interface{}) {
+	if z.zl.Core().Enabled(zap.InfoLevel) {
+		z.Info(c, s, keyValueFields(keysAndValues)...)
+	}
+}
+
+func (z *Logecho) Warnw(c echo.Context, s string, keysAndValues ...interface{}) {
+	if z.zl.Core().Enabled(zap.WarnLevel) {
+		z.Warn(c, s, keyValueFields(keysAndValues)...)
+	}
+}
+
+func (z *Logecho) Errorw(c echo.Context, s string, keysAndValues ...interface{}) {
+	if z.zl.Core().Enabled(zap.ErrorLevel) {
+		z.Error(c, s, keyValueFields(keysAndValues)...)
+	}
+}
+
+// badKey is the key to values who has no string key
+const badKey = "!BADKEY"
+
+// keyValueFields builds fields from loosely typed key-value pairs
+// like the zap.SugaredLogger does.
+//
+// A zapcore.Field is used as is. A value without a string key is
+// logged with the "!BADKEY" key
+func keyValueFields(keysAndValues []interface{}) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(keysAndValues)/2+1)
+	for i := 0; i < len(keysAndValues); i++ {
+		if f, ok := keysAndValues[i].(zapcore.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		key, ok := keysAndValues[i].(string)
+		if !ok || i == len(keysAndValues)-1 {
+			fields = append(fields, zap.Any(badKey, keysAndValues[i]))
+			continue
+		}
+
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+		i++
+	}
+
+	return fields
 }
@@ -0,0 +1,159 @@
+package logecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogechoFields(t *testing.T) {
+	t.Run("should merge call fields with template fields", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		l := newTestLogger(b)
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{
+			Logger: l,
+			Fields: Fields{"host": Host, "method": Method},
+		}))
+		e.GET("/", func(c echo.Context) error {
+			l.Info(c, "order created",
+				zap.String("order_id", "10"),
+				zap.String("host", "overridden"),
+			)
+			return c.NoContent(http.StatusOK)
+		})
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		raw := bytes.SplitN(b.Bytes(), []byte("\n"), 2)[0]
+		if n := bytes.Count(raw, []byte(`"host"`)); n != 1 {
+			t.Fatal("expected a single host key but got", n, string(raw))
+		}
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 {
+			t.Fatal("expected 2 log lines but got", len(lines))
+		}
+
+		line := lines[0]
+		if line["order_id"] != "10" {
+			t.Fatal("expected order_id 10 but got", line["order_id"])
+		}
+		if line["host"] != "overridden" {
+			t.Fatal("expected host overridden but got", line["host"])
+		}
+		if line["method"] != http.MethodGet {
+			t.Fatal("expected method GET but got", line["method"])
+		}
+	})
+}
+
+func TestMergeFields(t *testing.T) {
+	base := []zap.Field{zap.String("a", "1"), zap.String("b", "2")}
+
+	merged := mergeFields(base, []zap.Field{zap.String("b", "3"), zap.Int("c", 4)})
+	if len(merged) != 3 {
+		t.Fatal("expected 3 fields but got", len(merged))
+	}
+
+	for _, f := range merged {
+		if f.Key == "b" && f.String != "3" {
+			t.Fatal("expected b overridden by 3 but got", f.String)
+		}
+	}
+
+	if len(mergeFields(base, nil)) != 2 {
+		t.Fatal("expected base fields without overrides")
+	}
+}
+
+func TestLogechoFormatted(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger: l,
+		Fields: Fields{"method": Method},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		l.Errorf(c, "order %d failed", 10)
+		l.Warnw(c, "order delayed", "order_id", 10, zap.Bool("retry", true), "dangling")
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := decodeLines(t, b)
+	if len(lines) != 3 {
+		t.Fatal("expected 3 log lines but got", len(lines))
+	}
+
+	formatted := lines[0]
+	if formatted["msg"] != "order 10 failed" || formatted["level"] != "error" {
+		t.Fatal("expected formatted error log but got", formatted)
+	}
+	if formatted["method"] != http.MethodGet {
+		t.Fatal("expected template fields on formatted log but got", formatted)
+	}
+
+	kv := lines[1]
+	if kv["level"] != "warn" || kv["method"] != http.MethodGet {
+		t.Fatal("expected warn log with template fields but got", kv)
+	}
+	if kv["order_id"] != json.Number("10") || kv["retry"] != true || kv[badKey] != "dangling" {
+		t.Fatal("expected key value fields but got", kv)
+	}
+}
+
+func TestLogechoDisabledLevel(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+	l.level.SetLevel(zap.WarnLevel)
+
+	extracted := 0
+	counted := Field{tpl: "{{ .Counted }}", kind: reflect.String, extract: func(c echo.Context, key string) zapcore.Field {
+		extracted++
+		return zap.String(key, "counted")
+	}}
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:         l,
+		Fields:         Fields{"counted": counted},
+		EnableStartLog: true,
+	}))
+	e.GET("/", func(c echo.Context) error {
+		l.Debug(c, "debug")
+		l.Info(c, "info")
+		l.Log(c, zap.DebugLevel, "log")
+		l.Debugf(c, "debug %d", 1)
+		l.Infow(c, "info", "key", "value")
+
+		if extracted != 0 {
+			t.Fatal("expected no extraction at disabled levels but got", extracted)
+		}
+
+		l.Warn(c, "warn")
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if extracted != 1 {
+		t.Fatal("expected a single extraction to the warn log but got", extracted)
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || lines[0]["counted"] != "counted" {
+		t.Fatal("expected only the warn log with template fields but got", lines)
+	}
+}
@@ -1,6 +1,12 @@
 package logecho
 
-import "github.com/labstack/echo/v4"
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
 
 // MiddlewareConfig is a struct to set a custom
 // config to echo.Middleware if you want to remove or add a config.
@@ -33,17 +39,115 @@ type MiddlewareConfig struct {
 	//	- transaction requests -> 100
 	EnableRequestCount bool
 
+	// EnableStartLog will log "request started" when the request
+	// enters the middleware, with the Request class fields of Fields.
+	//
+	// Both the start log and the "handled request" log carry the
+	// request_id field to be paired, so EnableRequestID should be
+	// enabled too.
+	//
+	// Useful to find hung requests, who never log "handled request"
+	EnableStartLog bool
+
+	// StartMessage is the message of the start log.
+	//
+	// Default is "request started"
+	StartMessage string
+
+	// EnableRecover will recover panics on handlers. The panic is
+	// logged at ERROR with the panic value, the stack trace and the
+	// template fields, and turned into a 500 through c.Error.
+	// Requests skipped by Skipper and Exclude are recovered too.
+	//
+	// Request counters are kept balanced on panics even without it
+	EnableRecover bool
+
 	// EnableRequestID will enable transaction request ID. It will
 	// set request ID on the context and the response header X-Request-ID.
 	//
 	// Useful to tracing all logs from a single request.
 	EnableRequestID bool
 
+	// EnableTraceContext will continue the W3C Trace Context from
+	// traceparent and tracestate request headers in a new span, or
+	// start a new trace. The span is set as traceparent on response.
+	//
+	// TraceID, SpanID and ParentSpanID fields log it, and handlers
+	// can recover it with TraceIDFromContext and SpanIDFromContext
+	EnableTraceContext bool
+
+	// Propagation are the trace context formats of EnableTraceContext.
+	// The trace is extracted from the first format present on request
+	// headers, and injected on response headers of every format.
+	//
+	// Default is PropagationW3C
+	Propagation []Propagation
+
+	// RequestIDGenerator generates the request ids of requests who
+	// come without X-Request-ID. Built-in ones are RandomGenerator,
+	// UUIDv4Generator, UUIDv7Generator, ULIDGenerator and
+	// KSUIDGenerator, any func() string can be converted too:
+	//
+	//	RequestIDGenerator: logecho.RequestIDGenerator(myID)
+	//
+	// Default is a crypto random string of 12 alphanumeric chars
+	RequestIDGenerator RequestIDGenerator
+
+	// RequestIDHeader sets the request id header, its fallbacks and
+	// how incoming ids are validated.
+	//
+	// Default is X-Request-ID
+	RequestIDHeader CorrelationHeader
+
+	// TransactionIDHeader sets the transaction id header, its
+	// fallbacks and how incoming ids are validated.
+	//
+	// Default is x-transaction-id with transaction-id as fallback
+	TransactionIDHeader CorrelationHeader
+
 	// Fields will set how aditional fields will be printed on log
 	// messages.
 	//
 	// It set's the key and what will be logged
 	Fields Fields
+
+	// Skipper defines a function to skip logging a request. Skipped
+	// requests still get the request id and transaction id headers.
+	//
+	// Default is nil, who logs every request
+	Skipper Skipper
+
+	// Exclude declares requests who are skipped by method, path,
+	// route and glob. They are skipped like by Skipper
+	Exclude Exclusions
+
+	// Sampling keeps only part of the "handled request" logs, by
+	// status and latency rules.
+	//
+	// Default is nil, who logs every request
+	Sampling *AccessSampling
+
+	// HandledLog sets level and message of the "handled request" log
+	// by the request outcome, like status and latency.
+	//
+	// Default is nil, who logs every request as INFO. Look
+	// DefaultHandledLog to log errors and slow requests as ERROR and
+	// WARN
+	HandledLog *HandledLog
+
+	// ErrorKeys sets the keys of the handler error fields, who are
+	// added to the "handled request" log when the handler returns
+	// an error
+	ErrorKeys ErrorKeys
+
+	// Logger is the Logecho instance who writes the middleware logs.
+	//
+	// It is useful to run many echo servers in one process, each one
+	// with its own level, encoding and sinks. Handlers can recover it
+	// with FromEchoContext.
+	//
+	// Default is the Logger singleton
+	Logger *Logecho
 }
 
 var (
@@ -84,43 +188,186 @@ func MiddlewareWithTemplate(tpl Fields) echo.MiddlewareFunc {
 //
 // It will set default template if has no Fields in the config.
 //
-// Additional that it will log message "request done" on end call
+// Additional that it will log message "handled request" on end call,
+// with level and message by HandledLog
 func MiddlewareWithConfig(cfg MiddlewareConfig) echo.MiddlewareFunc {
 	if len(cfg.Fields) == 0 {
 		cfg.Fields = defaultTpl
 	}
 
+	// compile template once per middleware instance, requests only
+	// bind its values at log time
+	tpl := compileTemplate(cfg.Fields)
+	startTpl := tpl.requestClass()
+	sampler := newAccessSampler(cfg.Sampling)
+	skip := cfg.skipper()
+	handled := cfg.handledLog()
+	errorKeys := cfg.ErrorKeys.withDefaults()
+	generateID := cfg.requestIDGenerator()
+	requestIDHeader := newCorrelation(cfg.RequestIDHeader, defaultRequestIDHeader, requestIDKey)
+	transactionIDHeader := newCorrelation(cfg.TransactionIDHeader, defaultTransactionIDHeader, transactionIDKey)
+	propagation := newPropagators(cfg.Propagation)
+	outgoing := &outgoingHeaders{
+		requestID:     requestIDHeader.Name,
+		transactionID: transactionIDHeader.Name,
+		propagation:   propagation,
+	}
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			defer Logger.zl.Sync()
+			if skip != nil && skip(c) {
+				installTransactionID(c, transactionIDHeader)
+				if cfg.EnableRequestID {
+					installXRequestID(c, requestIDHeader, generateID)
+				}
+				if cfg.EnableTraceContext {
+					installTraceContext(c, propagation)
+				}
 
-			configTemplate(cfg.Fields, c)
-			installTransactionID(c)
+				// the logger, the template and the scope make the ids and
+				// fields reachable from handlers, they log nothing
+				logger := cfg.logger()
+				installTemplate(c, tpl)
+				installLogger(c, logger)
+				installScope(c, logger, outgoing)
+
+				// skipped requests are recovered too, echo turns the
+				// returned error into a 500
+				return callNext(next, c, logger, cfg.EnableRecover)
+			}
+
+			logger := cfg.logger()
+			start := time.Now()
+
+			installTemplate(c, tpl)
+			installLogger(c, logger)
+			installTransactionID(c, transactionIDHeader)
 
 			if cfg.EnableLatency {
 				initLatencyCalc(c)
 			}
 
 			if cfg.EnableRequestID {
-				installXRequestID(c)
+				installXRequestID(c, requestIDHeader, generateID)
+			}
+
+			if cfg.EnableTraceContext {
+				installTraceContext(c, propagation)
+			}
+
+			installScope(c, logger, outgoing)
+
+			var pair []zapcore.Field
+			if cfg.EnableStartLog {
+				pair = pairFields(c)
+				logStart(logger, startTpl, c, cfg.startMessage(), pair)
 			}
 
 			if cfg.EnableRequestCount {
 				incrementRequestCounter()
+				defer decrementRequestCounter()
 			}
 
 			var err error
-			if err = next(c); err != nil {
+			if err = callNext(next, c, logger, cfg.EnableRecover); err != nil {
 				c.Error(err)
 			}
 
-			Logger.Info(c, "handled request")
-
-			if cfg.EnableRequestCount {
-				decrementRequestCounter()
+			latency := time.Since(start)
+			if sampler.sample(c, latency) {
+				handled.write(logger, c, latency, append(errorKeys.fields(err), pair...)...)
 			}
+			sampler.report(logger)
 
 			return err
 		}
 	}
 }
+
+// logger returns the configured Logger or the Logger singleton
+// when it has no Logger
+func (cfg MiddlewareConfig) logger() *Logecho {
+	if cfg.Logger == nil {
+		return Logger
+	}
+
+	return cfg.Logger
+}
+
+// loggerKey is the echo.Context key where the middleware
+// stores the Logecho instance it uses
+const loggerKey = "logecho.logger"
+
+// installLogger sets the middleware Logecho on the context
+func installLogger(c echo.Context, l *Logecho) {
+	c.Set(loggerKey, l)
+}
+
+// FromEchoContext returns the Logecho instance the middleware used to
+// handle the request, so handlers log through the same instance.
+//
+// It returns the Logger singleton when the request was not handled
+// by the middleware.
+//
+// Example:
+//
+//	func handler(c echo.Context) error {
+//		logecho.FromEchoContext(c).Info(c, "started handler request")
+//		// ...
+//	}
+func FromEchoContext(c echo.Context) *Logecho {
+	if l, ok := c.Get(loggerKey).(*Logecho); ok {
+		return l
+	}
+
+	return Logger
+}
+
+// handledLog returns the configured HandledLog or a HandledLog who
+// logs every request as INFO when it has no HandledLog
+func (cfg MiddlewareConfig) handledLog() HandledLog {
+	if cfg.HandledLog == nil {
+		return HandledLog{}
+	}
+
+	return *cfg.HandledLog
+}
+
+// startMessage returns "request started" as default when StartMessage
+// is empty
+func (cfg MiddlewareConfig) startMessage() string {
+	if cfg.StartMessage == "" {
+		return "request started"
+	}
+
+	return cfg.StartMessage
+}
+
+// pairFields are the fields who pair the start log and the
+// "handled request" log of a request
+func pairFields(c echo.Context) []zapcore.Field {
+	if id := getXRequestID(c); id != "" {
+		return []zapcore.Field{zap.String("request_id", id)}
+	}
+
+	return nil
+}
+
+// logStart writes the start log with the fields of tpl, the fields
+// bound by With and fields. The fields are only extracted when INFO
+// is enabled
+func logStart(l *Logecho, tpl *fieldsTemplate, c echo.Context, msg string, fields []zapcore.Field) {
+	if ce := l.zl.Check(zap.InfoLevel, msg); ce != nil {
+		ce.Write(mergeFields(mergeFields(tpl.execute(c), getBoundFields(c)), fields)...)
+	}
+}
+
+// requestIDGenerator returns the default request id generator when
+// RequestIDGenerator is nil
+func (cfg MiddlewareConfig) requestIDGenerator() RequestIDGenerator {
+	if cfg.RequestIDGenerator == nil {
+		return defaultRequestIDGenerator
+	}
+
+	return cfg.RequestIDGenerator
+}
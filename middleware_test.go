@@ -0,0 +1,420 @@
+package logecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newTestLogger builds a Logecho who writes JSON logs into w
+func newTestLogger(w io.Writer) *Logecho {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	level := zap.NewAtomicLevelAt(zap.DebugLevel)
+
+	return newLogecho(zap.New(zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(w)), level)), level)
+}
+
+// useLogger replaces the Logger singleton until the test ends
+func useLogger(tb testing.TB, l *Logecho) {
+	old := Logger
+	SetLogger(l)
+	tb.Cleanup(func() { SetLogger(old) })
+}
+
+// decodeLines decodes each JSON log line written into b
+func decodeLines(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	dec := json.NewDecoder(b)
+	dec.UseNumber()
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal("expected valid json log line but got", err)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// jsonNumber is n as decoded by decodeLines
+func jsonNumber(n int) json.Number {
+	return json.Number(fmt.Sprint(n))
+}
+
+func serve(e *echo.Echo, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func okHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "ok")
+}
+
+func TestMiddlewareWithConfig(t *testing.T) {
+	t.Run("should keep fields template per middleware instance", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		useLogger(t, newTestLogger(b))
+
+		e := echo.New()
+		e.GET("/public/:id", okHandler, MiddlewareWithTemplate(Fields{
+			"public-id": Param("id"),
+		}))
+		e.GET("/admin", okHandler, MiddlewareWithTemplate(Fields{
+			"admin-method": Method,
+		}))
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/public/10", nil))
+		serve(e, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		serve(e, httptest.NewRequest(http.MethodGet, "/public/20", nil))
+
+		lines := decodeLines(t, b)
+		if len(lines) != 3 {
+			t.Fatal("expected 3 log lines but got", len(lines))
+		}
+
+		for i, id := range map[int]string{0: "10", 2: "20"} {
+			if lines[i]["public-id"] != id {
+				t.Fatal("expected public-id", id, "but got", lines[i]["public-id"])
+			}
+			if _, ok := lines[i]["admin-method"]; ok {
+				t.Fatal("expected no admin-method field on public log")
+			}
+		}
+
+		if lines[1]["admin-method"] != http.MethodGet {
+			t.Fatal("expected admin-method GET but got", lines[1]["admin-method"])
+		}
+		if _, ok := lines[1]["public-id"]; ok {
+			t.Fatal("expected no public-id field on admin log")
+		}
+	})
+
+	t.Run("should log typed fields", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		useLogger(t, newTestLogger(b))
+
+		e := echo.New()
+		e.GET("/users/:id", okHandler, MiddlewareWithTemplate(Fields{
+			"status":    Status,
+			"bytes-out": BytesOut,
+			"latency":   LatencyInNs,
+			"params":    Params,
+			"query":     Query,
+			"running":   RunningRequests,
+		}))
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/users/1?tag=a&tag=b&page=2", nil))
+
+		lines := decodeLines(t, b)
+		if len(lines) != 1 {
+			t.Fatal("expected 1 log line but got", len(lines))
+		}
+
+		line := lines[0]
+		for _, key := range []string{"status", "bytes-out", "latency", "running"} {
+			n, ok := line[key].(json.Number)
+			if !ok {
+				t.Fatal("expected", key, "as number but got", line[key])
+			}
+			if _, err := n.Int64(); err != nil {
+				t.Fatal("expected", key, "as integer but got", n)
+			}
+		}
+
+		if line["status"] != json.Number("200") {
+			t.Fatal("expected status 200 but got", line["status"])
+		}
+
+		params, ok := line["params"].(map[string]interface{})
+		if !ok || params["id"] != "1" {
+			t.Fatal("expected params object with id but got", line["params"])
+		}
+
+		query, ok := line["query"].(map[string]interface{})
+		if !ok || query["page"] != "2" || len(query["tag"].([]interface{})) != 2 {
+			t.Fatal("expected query object but got", line["query"])
+		}
+	})
+
+	t.Run("should escape hostile values", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		useLogger(t, newTestLogger(b))
+
+		hostile := `x","admin":"true\"}\n{"level":"fatal`
+		hostileParam := `x","admin":"true\`
+		hostileCookie := `x,admin:true}{`
+
+		e := echo.New()
+		e.GET("/users/:id", okHandler, MiddlewareWithTemplate(Fields{
+			"origin":     Header("x-origin"),
+			"id":         Param("id"),
+			"session":    Cookie("session"),
+			"user-agent": UserAgent,
+			"params":     Params,
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/"+hostileParam, nil)
+		req.Header.Set("x-origin", hostile)
+		req.Header.Set("User-Agent", hostile)
+		req.Header.Set("Cookie", "session="+hostileCookie)
+		serve(e, req)
+
+		lines := decodeLines(t, b)
+		if len(lines) != 1 {
+			t.Fatal("expected 1 log line but got", len(lines))
+		}
+
+		line := lines[0]
+		if _, ok := line["admin"]; ok {
+			t.Fatal("expected no injected admin key but got", line)
+		}
+
+		if line["level"] != "info" {
+			t.Fatal("expected level info but got", line["level"])
+		}
+
+		for _, key := range []string{"origin", "user-agent"} {
+			if line[key] != hostile {
+				t.Fatal("expected", key, "as", hostile, "but got", line[key])
+			}
+		}
+
+		if line["id"] != hostileParam {
+			t.Fatal("expected id as", hostileParam, "but got", line["id"])
+		}
+
+		params, ok := line["params"].(map[string]interface{})
+		if !ok || params["id"] != hostileParam {
+			t.Fatal("expected params id as", hostileParam, "but got", line["params"])
+		}
+
+		if line["session"] != hostileCookie {
+			t.Fatal("expected raw session cookie but got", line["session"])
+		}
+	})
+
+	t.Run("should write through configured Logger", func(t *testing.T) {
+		global := new(bytes.Buffer)
+		useLogger(t, newTestLogger(global))
+
+		public, admin := new(bytes.Buffer), new(bytes.Buffer)
+		publicLogger, adminLogger := newTestLogger(public), newTestLogger(admin)
+
+		handler := func(c echo.Context) error {
+			FromEchoContext(c).Info(c, "inside handler")
+			return c.NoContent(http.StatusOK)
+		}
+
+		publicAPI := echo.New()
+		publicAPI.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: publicLogger}))
+		publicAPI.GET("/", handler)
+
+		adminAPI := echo.New()
+		adminAPI.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: adminLogger}))
+		adminAPI.GET("/", handler)
+
+		serve(publicAPI, httptest.NewRequest(http.MethodGet, "/", nil))
+		serve(adminAPI, httptest.NewRequest(http.MethodGet, "/", nil))
+		serve(adminAPI, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if lines := decodeLines(t, public); len(lines) != 2 {
+			t.Fatal("expected 2 public log lines but got", len(lines))
+		}
+		if lines := decodeLines(t, admin); len(lines) != 4 {
+			t.Fatal("expected 4 admin log lines but got", len(lines))
+		}
+		if global.Len() != 0 {
+			t.Fatal("expected no log on Logger singleton but got", global.String())
+		}
+	})
+
+	t.Run("should write through replaced Logger singleton", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		useLogger(t, newTestLogger(b))
+
+		e := echo.New()
+		e.GET("/", func(c echo.Context) error {
+			if FromEchoContext(c) != Logger {
+				t.Fatal("expected Logger singleton from context")
+			}
+			return c.NoContent(http.StatusOK)
+		}, Middleware())
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if lines := decodeLines(t, b); len(lines) != 1 {
+			t.Fatal("expected 1 log line but got", len(lines))
+		}
+	})
+
+	t.Run("should log no template fields without middleware", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		l := newTestLogger(b)
+
+		l.Info(NewContext(), "no middleware")
+
+		lines := decodeLines(t, b)
+		if len(lines) != 1 {
+			t.Fatal("expected 1 log line but got", len(lines))
+		}
+		if _, ok := lines[0]["host"]; ok {
+			t.Fatal("expected no template fields but got", lines[0])
+		}
+	})
+}
+
+func BenchmarkMiddlewareWithConfig(b *testing.B) {
+	useLogger(b, newTestLogger(io.Discard))
+
+	e := echo.New()
+	e.GET("/users/:id", okHandler, MiddlewareWithTemplate(Fields{
+		"host":    Host,
+		"method":  Method,
+		"path":    Path,
+		"id":      Param("id"),
+		"origin":  Header("x-origin"),
+		"latency": LatencyInMs,
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serve(e, req)
+	}
+}
+
+// overlapWriter is a plain io.Writer who counts overlapping Write
+// calls. Logs written through it must be serialized by the logger
+type overlapWriter struct {
+	b        bytes.Buffer
+	writing  int32
+	overlaps int32
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if !atomic.CompareAndSwapInt32(&w.writing, 0, 1) {
+		atomic.AddInt32(&w.overlaps, 1)
+		return len(p), nil
+	}
+	defer atomic.StoreInt32(&w.writing, 0)
+
+	// yield to widen the window where an unserialized Write overlaps
+	runtime.Gosched()
+
+	return w.b.Write(p)
+}
+
+func TestMiddlewareConcurrency(t *testing.T) {
+	w := new(overlapWriter)
+	l, err := New(Config{Level: zap.DebugLevel, Outputs: []Output{{Writer: w}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:             l,
+		EnableLatency:      true,
+		EnableRequestID:    true,
+		EnableRequestCount: true,
+		Fields: Fields{
+			"id":      Param("id"),
+			"params":  Params,
+			"latency": LatencyInNs,
+			"running": RunningRequests,
+		},
+	}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		With(c, zap.String("user_id", c.Param("id")))
+		l.Info(c, "inside handler")
+		FromContext(c.Request().Context()).Info("from context")
+		return c.NoContent(http.StatusOK)
+	})
+
+	const workers, requests = 16, 50
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				serve(e, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d-%d", w, i), nil))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if w.overlaps != 0 {
+		t.Fatal("expected serialized writes but got", w.overlaps, "overlapping writes")
+	}
+
+	lines := decodeLines(t, &w.b)
+	if len(lines) != workers*requests*3 {
+		t.Fatal("expected", workers*requests*3, "log lines but got", len(lines))
+	}
+
+	for _, line := range lines {
+		if line["id"] != nil && line["id"] != line["user_id"] {
+			t.Fatal("expected fields from the same request but got", line)
+		}
+	}
+}
+
+func BenchmarkMiddlewareWithConfigParallel(b *testing.B) {
+	useLogger(b, newTestLogger(io.Discard))
+
+	e := echo.New()
+	e.GET("/users/:id", okHandler, MiddlewareWithTemplate(Fields{
+		"host":    Host,
+		"method":  Method,
+		"path":    Path,
+		"id":      Param("id"),
+		"origin":  Header("x-origin"),
+		"latency": LatencyInMs,
+	}))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		for pb.Next() {
+			serve(e, req)
+		}
+	})
+}
+
+func BenchmarkCompileTemplate(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		compileTemplate(defaultTpl)
+	}
+}
+
+func BenchmarkExecuteTemplate(b *testing.B) {
+	tpl := compileTemplate(defaultTpl)
+	c := NewContext()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tpl.execute(c)
+	}
+}
@@ -0,0 +1,157 @@
+package logecho
+
+import (
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Output defines where logs are written to.
+//
+// Example, colored console output to the terminal and full JSON to
+// a file:
+//
+//	logecho.Config{
+//		Level: zap.DebugLevel,
+//		Outputs: []logecho.Output{
+//			{
+//				Path:        logecho.Stdout,
+//				Encoding:    logecho.Text,
+//				EncodeLevel: zapcore.LowercaseColorLevelEncoder,
+//				Level:       zap.InfoLevel,
+//			},
+//			{Path: "/var/log/app.json", Encoding: logecho.JSON},
+//		},
+//	}
+type Output struct {
+	// Path is the file path to write logs. It can be Stdout or
+	// Stderr too.
+	//
+	// Path is ignored when Writer is set
+	Path string
+
+	// Writer is any io.Writer to write logs
+	Writer io.Writer
+
+	// Rotation enables rotation of the Path file. It has no effect
+	// to Writer, use NewRotatingFile to rotate a Writer.
+	//
+	// Default is nil, who never rotates the file
+	Rotation *Rotation
+
+	// Level sets the minimum level of logs who go to that output.
+	//
+	// Any zapcore.Level fits here. Logs must pass the Config Level
+	// too. Default is nil, who writes every log enabled by Config
+	// Level
+	Level zapcore.LevelEnabler
+
+	// Encoding changes the log format of that output.
+	//
+	// Default is Config Encoding
+	Encoding Encoding
+
+	// EncodeLevel defines how the level will be printed on that
+	// output.
+	//
+	// Default is Config EncodeLevel
+	EncodeLevel zapcore.LevelEncoder
+}
+
+const (
+	// Stdout is the Output Path to write logs to standard output
+	Stdout = "stdout"
+	// Stderr is the Output Path to write logs to standard error
+	Stderr = "stderr"
+)
+
+// defaultOutputs is used when Config has no Outputs
+var defaultOutputs = []Output{{Path: Stderr}}
+
+// outputLevel enables a level when both the logger level and the
+// output level enable it
+type outputLevel struct {
+	logger zapcore.LevelEnabler
+	output zapcore.LevelEnabler
+}
+
+func (l outputLevel) Enabled(level zapcore.Level) bool {
+	if l.output != nil && !l.output.Enabled(level) {
+		return false
+	}
+
+	return l.logger.Enabled(level)
+}
+
+// validate checks that output has somewhere to write and a known
+// Encoding. It returns the problems found
+func (o Output) validate(i int) []string {
+	var problems []string
+
+	if o.Writer == nil && o.Path == "" {
+		problems = append(problems, fmt.Sprintf("Outputs[%d] has no Path or Writer", i))
+	}
+
+	if o.Rotation != nil && (o.Writer != nil || o.Path == "" || o.Path == Stdout || o.Path == Stderr) {
+		problems = append(problems, fmt.Sprintf("Outputs[%d] has Rotation but no file Path", i))
+	}
+
+	switch o.Encoding {
+	case "", JSON, Text:
+	default:
+		problems = append(problems, fmt.Sprintf(`Outputs[%d] has unknown Encoding "%s"`, i, o.Encoding))
+	}
+
+	return problems
+}
+
+// sink opens the output WriteSyncer. The close func releases the
+// opened file, if any.
+//
+// Writer is locked, as a plain io.Writer is not safe for concurrent
+// writes
+func (o Output) sink() (zapcore.WriteSyncer, func(), error) {
+	if o.Writer != nil {
+		return zapcore.Lock(zapcore.AddSync(o.Writer)), func() {}, nil
+	}
+
+	if o.Rotation != nil {
+		file := NewRotatingFile(o.Path, *o.Rotation)
+		if err := file.openNow(); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		return zapcore.AddSync(file), func() { file.Close() }, nil
+	}
+
+	return zap.Open(o.Path)
+}
+
+// core builds the output zapcore.Core. encCfg and encoding are the
+// Config defaults to output and open opens the output sink
+func (o Output) core(encCfg zapcore.EncoderConfig, encoding Encoding, level zapcore.LevelEnabler, open func(Output) (zapcore.WriteSyncer, error)) (zapcore.Core, error) {
+	ws, err := open(o)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.EncodeLevel != nil {
+		encCfg.EncodeLevel = o.EncodeLevel
+	}
+
+	if o.Encoding != "" {
+		encoding = o.Encoding
+	}
+
+	var encoder zapcore.Encoder
+	if encoding == Text {
+		encoder = zapcore.NewConsoleEncoder(encCfg)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encCfg)
+	}
+
+	return zapcore.NewCore(encoder, ws, outputLevel{level, o.Level}), nil
+}
@@ -0,0 +1,82 @@
+package logecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOutputs(t *testing.T) {
+	t.Run("should write to each output with its level and encoding", func(t *testing.T) {
+		console, full := new(bytes.Buffer), new(bytes.Buffer)
+		file := filepath.Join(t.TempDir(), "app.json")
+
+		z, err := New(Config{
+			Level: zap.DebugLevel,
+			Outputs: []Output{
+				{Writer: console, Encoding: Text, EncodeLevel: zapcore.CapitalLevelEncoder, Level: zap.WarnLevel},
+				{Writer: full, Encoding: JSON},
+				{Path: file, Level: zap.ErrorLevel},
+			},
+		})
+		if err != nil {
+			t.Fatal("expected no error but got", err)
+		}
+
+		l := z.NoContext()
+		l.Debug("debug message")
+		l.Warn("warn message")
+		l.Error("error message")
+		z.zl.Sync()
+
+		if strings.Contains(console.String(), "debug message") || !strings.Contains(console.String(), "WARN\twarn message") {
+			t.Fatal("expected warn and error console logs but got", console.String())
+		}
+
+		if lines := decodeLines(t, full); len(lines) != 3 || lines[0]["level"] != "debug" {
+			t.Fatal("expected all json logs but got", lines)
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal("expected log file but got", err)
+		}
+
+		var line map[string]interface{}
+		if err := json.Unmarshal(b, &line); err != nil || line["message"] != "error message" {
+			t.Fatal("expected only error log on file but got", string(b))
+		}
+	})
+
+	t.Run("should follow runtime level", func(t *testing.T) {
+		b := new(bytes.Buffer)
+
+		z, _ := New(Config{Outputs: []Output{{Writer: b}}})
+		z.NoContext().Debug("hidden")
+		z.SetLevel(zap.DebugLevel)
+		z.NoContext().Debug("shown")
+
+		if lines := decodeLines(t, b); len(lines) != 1 || lines[0]["message"] != "shown" {
+			t.Fatal("expected only debug log after SetLevel but got", lines)
+		}
+	})
+
+	t.Run("should report invalid outputs", func(t *testing.T) {
+		_, err := New(Config{Outputs: []Output{{}, {Path: Stdout, Encoding: "xml"}}})
+
+		if err == nil || !strings.Contains(err.Error(), "Outputs[0] has no Path") || !strings.Contains(err.Error(), "Outputs[1] has unknown Encoding") {
+			t.Fatal("expected invalid outputs error but got", err)
+		}
+
+		_, err = New(Config{Outputs: []Output{{Path: filepath.Join(t.TempDir(), "missing", "app.log")}}})
+		if err == nil {
+			t.Fatal("expected error to unwritable path")
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package logecho
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	// panicKey is the key of the recovered panic value
+	panicKey = "panic"
+	// panicStackKey is the key of the recovered panic stack trace
+	panicStackKey = "panic_stack"
+)
+
+// callNext calls the next handler. With recovery it recovers a panic
+// on next, logs it at ERROR with the panic value, the stack trace and
+// the template fields, and returns it as error.
+//
+// http.ErrAbortHandler is not recovered, as net/http expects it
+func callNext(next echo.HandlerFunc, c echo.Context, l *Logecho, recovery bool) (err error) {
+	if recovery {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			l.Error(c, "recovered panic",
+				zap.Any(panicKey, r),
+				zap.ByteString(panicStackKey, debug.Stack()),
+			)
+
+			err = recoveredError(r)
+		}()
+	}
+
+	return next(c)
+}
+
+// recoveredError turns the recovered value into an error who keeps
+// the value when it is an error
+func recoveredError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("[PANIC RECOVER] %w", err)
+	}
+
+	return fmt.Errorf("[PANIC RECOVER] %v", r)
+}
@@ -0,0 +1,125 @@
+package logecho
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddlewareRecover(t *testing.T) {
+	t.Run("should recover panic and log it", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		l := newTestLogger(b)
+		running := CurrentCount()
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{
+			Logger:             l,
+			EnableRecover:      true,
+			EnableRequestCount: true,
+			Fields:             Fields{"path": Path},
+		}))
+		e.GET("/panic", func(c echo.Context) error {
+			panic("handler exploded")
+		})
+
+		rec := serve(e, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatal("expected status 500 but got", rec.Code)
+		}
+		if CurrentCount() != running {
+			t.Fatal("expected running requests balanced but got", CurrentCount())
+		}
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 {
+			t.Fatal("expected 2 log lines but got", len(lines))
+		}
+
+		panicLine := lines[0]
+		if panicLine["level"] != "error" || panicLine[panicKey] != "handler exploded" || panicLine["path"] != "/panic" {
+			t.Fatal("expected error log with panic and template fields but got", panicLine)
+		}
+		if stack, _ := panicLine[panicStackKey].(string); !strings.Contains(stack, "recover_test.go") {
+			t.Fatal("expected stack trace to the panic but got", stack)
+		}
+
+		if lines[1]["error"] != "[PANIC RECOVER] handler exploded" {
+			t.Fatal("expected recovered error on handled request log but got", lines[1])
+		}
+	})
+
+	t.Run("should keep counters balanced without recover", func(t *testing.T) {
+		l := newTestLogger(new(bytes.Buffer))
+		running := CurrentCount()
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: l, EnableRequestCount: true}))
+		e.GET("/panic", func(c echo.Context) error {
+			panic("handler exploded")
+		})
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic to propagate")
+				}
+			}()
+			serve(e, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		}()
+
+		if CurrentCount() != running {
+			t.Fatal("expected running requests balanced but got", CurrentCount())
+		}
+	})
+
+	t.Run("should not recover ErrAbortHandler", func(t *testing.T) {
+		l := newTestLogger(new(bytes.Buffer))
+
+		e := echo.New()
+		e.Use(MiddlewareWithConfig(MiddlewareConfig{Logger: l, EnableRecover: true}))
+		e.GET("/abort", func(c echo.Context) error {
+			panic(http.ErrAbortHandler)
+		})
+
+		defer func() {
+			if recover() != http.ErrAbortHandler {
+				t.Fatal("expected ErrAbortHandler to propagate")
+			}
+		}()
+		serve(e, httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+}
+
+func TestMiddlewareRecoverSkipped(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:        newTestLogger(b),
+		EnableRecover: true,
+		Fields:        Fields{"method": Method, "path": Path},
+		Exclude:       Exclusions{Paths: []string{"/health"}},
+	}))
+	e.GET("/health", func(c echo.Context) error {
+		panic("health exploded")
+	})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatal("expected status 500 but got", rec.Code)
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || lines[0]["msg"] != "recovered panic" || lines[0]["panic"] != "health exploded" {
+		t.Fatal("expected only the recovered panic log but got", lines)
+	}
+	if lines[0]["method"] != http.MethodGet || lines[0]["path"] != "/health" {
+		t.Fatal("expected template fields on the recovered panic log but got", lines[0])
+	}
+}
@@ -1,95 +1,257 @@
 package logecho
 
 import (
-	"math/rand"
+	"fmt"
+	"net/http"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
-const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 const (
-	letterIdxBits = 6                    // 6 bits to represent a letter index
-	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-	letterIdxMax  = 63 / letterIdxBits   // Num of letter idx fitting in 63 bits
+	// requestIDKey and transactionIDKey are the echo.Context keys
+	// where the middleware stores the installed correlation ids
+	requestIDKey     = "logecho.request_id"
+	transactionIDKey = "logecho.transaction_id"
+
+	// defaultCorrelationMaxLength is the default CorrelationHeader
+	// MaxLength
+	defaultCorrelationMaxLength = 128
+
+	// defaultCorrelationCharset is the default CorrelationHeader
+	// Charset. It fits UUIDs, ULIDs, KSUIDs and base64 ids
+	defaultCorrelationCharset = Alphanumeric + "-_.:+/=@"
 )
 
-func generateRequestID() string {
-	return generateRequestIDWithCustomLength(12)
+// CorrelationHeader sets the header of a correlation id, like the
+// request id or the transaction id.
+//
+// Incoming ids are only accepted when they have up to MaxLength chars
+// of Charset. Ids who fail are regenerated, so a client can not write
+// huge or newline-laden ids into logs and response headers
+type CorrelationHeader struct {
+	// Name is the header read from the request and set on the response
+	Name string
+
+	// Fallbacks are request headers read in order when Name is not
+	// present. They are never set on the response
+	Fallbacks []string
+
+	// IgnoreIncoming generates a new id to every request, without
+	// trusting ids from request headers
+	IgnoreIncoming bool
+
+	// MaxLength is the longest incoming id accepted.
+	//
+	// Default is 128
+	MaxLength int
+
+	// Charset are the chars accepted in incoming ids.
+	//
+	// Default is ASCII letters, digits and "-_.:+/=@"
+	Charset string
 }
 
-// generateRequestID will generate randomic string mixed by
-// letterBytes chars
-func generateRequestIDWithCustomLength(length int) string {
-	rand.Seed(time.Now().UnixNano())
-	requestID := make([]byte, length)
+var (
+	defaultRequestIDHeader     = CorrelationHeader{Name: echo.HeaderXRequestID}
+	defaultTransactionIDHeader = CorrelationHeader{Name: "x-transaction-id", Fallbacks: []string{"transaction-id"}}
+)
+
+// correlation is a compiled CorrelationHeader who installs the id
+// on echo.Context key
+type correlation struct {
+	CorrelationHeader
 
-	for i, cache, remain := (length - 1), rand.Int63(), letterIdxMax; i >= 0; {
-		if remain == 0 {
-			cache, remain = rand.Int63(), letterIdxMax
+	key     string
+	allowed [256]bool
+}
+
+// newCorrelation compiles h with the defaults from def. It panics when
+// h is invalid, as it is a set up mistake
+func newCorrelation(h CorrelationHeader, def CorrelationHeader, key string) *correlation {
+	if h.Name == "" {
+		h.Name = def.Name
+		if h.Fallbacks == nil {
+			h.Fallbacks = def.Fallbacks
 		}
+	}
+
+	if h.MaxLength == 0 {
+		h.MaxLength = defaultCorrelationMaxLength
+	}
+
+	if h.Charset == "" {
+		h.Charset = defaultCorrelationCharset
+	}
+
+	for _, name := range append([]string{h.Name}, h.Fallbacks...) {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			panic(fmt.Sprintf("logecho: invalid correlation header name %q", name))
+		}
+	}
+
+	if h.MaxLength < 0 {
+		panic(fmt.Sprintf("logecho: correlation header %s MaxLength %d should not be negative", h.Name, h.MaxLength))
+	}
+
+	cor := &correlation{CorrelationHeader: h, key: key}
+	for i := 0; i < len(h.Charset); i++ {
+		cor.allowed[h.Charset[i]] = true
+	}
+
+	return cor
+}
+
+// incoming returns the first valid id of the request headers. It is
+// empty when the ids are ignored or no header has a valid id
+func (cor *correlation) incoming(c echo.Context) string {
+	if cor.IgnoreIncoming {
+		return ""
+	}
+
+	header := c.Request().Header
+	for _, name := range append([]string{cor.Name}, cor.Fallbacks...) {
+		if id := header.Get(name); id != "" {
+			if cor.valid(id) {
+				return id
+			}
+
+			return ""
+		}
+	}
+
+	return ""
+}
 
-		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
-			requestID[i] = letterBytes[idx]
-			i--
+// valid checks id length and chars
+func (cor *correlation) valid(id string) bool {
+	if len(id) > cor.MaxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if !cor.allowed[id[i]] {
+			return false
 		}
+	}
+
+	return true
+}
+
+// install will get the id incoming from headers and set it on
+// response and echo.Context.
+//
+// In case of already has id on response just keep it.
+//
+// In case of has not a valid id on incoming headers and response
+// headers, generate a new one with generate
+func (cor *correlation) install(c echo.Context, generate RequestIDGenerator) {
+	id := cor.incoming(c)
+	if id == "" {
+		id = c.Response().Header().Get(cor.Name)
+	}
 
-		cache >>= letterIdxBits
-		remain--
+	if id == "" {
+		id = generate()
 	}
 
-	return string(requestID)
+	c.Response().Header().Set(cor.Name, id)
+	c.Set(cor.key, id)
 }
 
 func getXRequestID(c echo.Context) string {
-	return c.Response().Header().Get(echo.HeaderXRequestID)
+	if id, ok := c.Get(requestIDKey).(string); ok {
+		return id
+	}
+
+	return c.Response().Header().Get(defaultRequestIDHeader.Name)
 }
 
 func getTransactionID(c echo.Context) string {
-	return c.Response().Header().Get("x-transaction-id")
+	if id, ok := c.Get(transactionIDKey).(string); ok {
+		return id
+	}
+
+	return c.Response().Header().Get(defaultTransactionIDHeader.Name)
 }
 
-// installXRequestID will get request id incoming from headers.
-// If it already set in Request just set it into response too.
-//
-// In case of already has request on response just ignore.
+// installXRequestID will get request id incoming from headers
+// of cor and set it into response too.
 //
 // In case of has not request id on incoming headers and response
-// headers, generate a new one and set it on response
-func installXRequestID(c echo.Context) {
-	reqID := c.Request().Header.Get(echo.HeaderXRequestID)
+// headers, generate a new one with generate and set it on response
+func installXRequestID(c echo.Context, cor *correlation, generate RequestIDGenerator) {
+	cor.install(c, generate)
+}
 
-	if reqID != "" {
-		c.Response().Header().Set(echo.HeaderXRequestID, reqID)
-		return
+// installTransactionID will get transaction id incoming from headers
+// of cor, or generate a new UUID, and set it into response
+func installTransactionID(c echo.Context, cor *correlation) {
+	cor.install(c, uuid.NewString)
+}
+
+// Propagation is a trace context propagation format
+type Propagation string
+
+const (
+	PropagationW3C     Propagation = "w3c"     // W3C traceparent and tracestate headers
+	PropagationB3      Propagation = "b3"      // Zipkin B3 single header b3
+	PropagationB3Multi Propagation = "b3multi" // Zipkin B3 multi headers X-B3-TraceId, X-B3-SpanId and X-B3-Sampled
+)
+
+// propagator extracts and injects the trace of a Propagation format
+type propagator struct {
+	extract func(h http.Header) *traceContext
+	inject  func(h http.Header, trace *traceContext)
+}
+
+var propagators = map[Propagation]propagator{
+	PropagationW3C:     {extractW3C, injectW3C},
+	PropagationB3:      {extractB3, injectB3},
+	PropagationB3Multi: {extractB3Multi, injectB3Multi},
+}
+
+// newPropagators returns the propagators of formats. Default is W3C.
+// It panics to unknown formats, as it is a set up mistake
+func newPropagators(formats []Propagation) []propagator {
+	if len(formats) == 0 {
+		formats = []Propagation{PropagationW3C}
 	}
 
-	if reqID := c.Response().Header().Get(echo.HeaderXRequestID); reqID != "" {
-		return
+	p := make([]propagator, 0, len(formats))
+	for _, format := range formats {
+		prop, ok := propagators[format]
+		if !ok {
+			panic(fmt.Sprintf("logecho: unknown trace propagation %q", format))
+		}
+
+		p = append(p, prop)
 	}
 
-	reqID = generateRequestID()
-	c.Response().Header().Set(echo.HeaderXRequestID, reqID)
+	return p
 }
 
-func installTransactionID(c echo.Context) {
-	header := "x-transaction-id"
-	transactionID := c.Request().Header.Get(header)
-	if transactionID == "" {
-		transactionID = c.Request().Header.Get(strings.TrimPrefix(header, "x-"))
+// installTraceContext continues the trace from the request headers of
+// the first format present in a new span, or starts a new trace.
+// The new span is set on echo.Context and on response headers of
+// every format
+func installTraceContext(c echo.Context, formats []propagator) {
+	if getTrace(c) != nil {
+		return
 	}
 
-	if transactionID != "" {
-		c.Response().Header().Set(header, transactionID)
-		return
+	var parent *traceContext
+	for _, format := range formats {
+		if parent = format.extract(c.Request().Header); parent != nil {
+			break
+		}
 	}
 
-	if transactionID := c.Response().Header().Get(header); transactionID != "" {
-		return
+	trace := newTraceContext(parent)
+	for _, format := range formats {
+		format.inject(c.Response().Header(), trace)
 	}
 
-	transactionID = uuid.NewString()
-	c.Response().Header().Set(header, transactionID)
+	c.Set(traceKey, trace)
 }
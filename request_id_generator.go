@@ -0,0 +1,162 @@
+package logecho
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDGenerator generates a request id. It is called once per
+// request who comes without request id, so it should be safe for
+// concurrent use.
+//
+// Any func() string can be a custom generator:
+//
+//	logecho.RequestIDGenerator(func() string { return myID() })
+type RequestIDGenerator func() string
+
+// Alphanumeric is the default alphabet to RandomGenerator
+const Alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+var (
+	UUIDv4Generator RequestIDGenerator = uuid.NewString // Built-in generator to random UUIDs, version 4
+	UUIDv7Generator RequestIDGenerator = newUUIDv7      // Built-in generator to time ordered UUIDs, version 7
+	ULIDGenerator   RequestIDGenerator = newULID        // Built-in generator to ULIDs, 26 chars in Crockford base32
+	KSUIDGenerator  RequestIDGenerator = newKSUID       // Built-in generator to KSUIDs, 27 chars in base62
+
+	defaultRequestIDGenerator = RandomGenerator(12, Alphanumeric)
+)
+
+const (
+	crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	base62Alphabet  = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// ksuidEpoch is the KSUID timestamp epoch, 2014-05-13T16:53:20Z
+	ksuidEpoch = 1400000000
+)
+
+// RandomGenerator builds a generator of crypto random ids with length
+// chars from alphabet. An empty alphabet is Alphanumeric.
+//
+// Every char of alphabet has the same chance. It panics when length
+// is not positive or alphabet has less than 2 or more than 256 chars
+func RandomGenerator(length int, alphabet string) RequestIDGenerator {
+	if alphabet == "" {
+		alphabet = Alphanumeric
+	}
+
+	if length <= 0 {
+		panic(fmt.Sprintf("logecho: request id length %d should be positive", length))
+	}
+
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		panic(fmt.Sprintf("logecho: request id alphabet should have 2 to 256 chars, got %d", len(alphabet)))
+	}
+
+	// mask is the lowest all 1-bits number who covers an alphabet
+	// index. Bytes out of alphabet are rejected, so ids are unbiased
+	mask := byte(1)
+	for int(mask) < len(alphabet)-1 {
+		mask = mask<<1 | 1
+	}
+
+	return func() string {
+		id := make([]byte, 0, length)
+		buf := make([]byte, length+length/2)
+
+		for len(id) < length {
+			readRandom(buf)
+
+			for _, b := range buf {
+				if idx := int(b & mask); idx < len(alphabet) {
+					id = append(id, alphabet[idx])
+					if len(id) == length {
+						break
+					}
+				}
+			}
+		}
+
+		return string(id)
+	}
+}
+
+// readRandom fills b from crypto/rand. It panics when the system
+// random source fails, as uuid.New does
+func readRandom(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("logecho: read random: %v", err))
+	}
+}
+
+// newUUIDv7 generates an UUID version 7. It has the unix time in
+// milliseconds in the first 48 bits, followed by random bits
+func newUUIDv7() string {
+	var id uuid.UUID
+	readRandom(id[6:])
+	putMillis(id[:6], time.Now())
+
+	id[6] = id[6]&0x0f | 0x70 // version 7
+	id[8] = id[8]&0x3f | 0x80 // variant RFC 4122
+
+	return id.String()
+}
+
+// newULID generates an ULID. It has the unix time in milliseconds in
+// the first 48 bits, followed by 80 random bits
+func newULID() string {
+	var id [16]byte
+	readRandom(id[6:])
+	putMillis(id[:6], time.Now())
+
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	// 26 chars of 5 bits hold the 128 bits, the first char only
+	// holds the highest 3 bits
+	out := make([]byte, 26)
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out)
+}
+
+// newKSUID generates a KSUID. It has the seconds since ksuidEpoch in
+// the first 32 bits, followed by 128 random bits
+func newKSUID() string {
+	var id [20]byte
+	readRandom(id[4:])
+	binary.BigEndian.PutUint32(id[:4], uint32(time.Now().Unix()-ksuidEpoch))
+
+	// 27 base62 chars hold the 160 bits. Each round divides the
+	// big-endian number by 62 and takes the remainder
+	out := make([]byte, 27)
+	for i := len(out) - 1; i >= 0; i-- {
+		var rem uint
+		for j, b := range id {
+			acc := rem<<8 | uint(b)
+			id[j] = byte(acc / 62)
+			rem = acc % 62
+		}
+
+		out[i] = base62Alphabet[rem]
+	}
+
+	return string(out)
+}
+
+// putMillis puts the unix time of t in milliseconds as 48 bits
+// big-endian into b
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
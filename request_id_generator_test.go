@@ -0,0 +1,136 @@
+package logecho
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestIDGenerators(t *testing.T) {
+	generators := []struct {
+		name     string
+		generate RequestIDGenerator
+		format   *regexp.Regexp
+	}{
+		{"default", defaultRequestIDGenerator, regexp.MustCompile(`^[a-zA-Z0-9]{12}$`)},
+		{"random", RandomGenerator(32, "abc"), regexp.MustCompile(`^[abc]{32}$`)},
+		{"uuidv4", UUIDv4Generator, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{"uuidv7", UUIDv7Generator, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{"ulid", ULIDGenerator, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+		{"ksuid", KSUIDGenerator, regexp.MustCompile(`^[0-9A-Za-z]{27}$`)},
+	}
+
+	const goroutines, perGoroutine = 16, 500
+
+	for _, g := range generators {
+		t.Run(g.name, func(t *testing.T) {
+			var (
+				wg  sync.WaitGroup
+				mu  sync.Mutex
+				ids = make([]string, 0, goroutines*perGoroutine)
+			)
+
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					generated := make([]string, 0, perGoroutine)
+					for j := 0; j < perGoroutine; j++ {
+						generated = append(generated, g.generate())
+					}
+
+					mu.Lock()
+					ids = append(ids, generated...)
+					mu.Unlock()
+				}()
+			}
+
+			wg.Wait()
+
+			seen := make(map[string]struct{}, len(ids))
+			for _, id := range ids {
+				if !g.format.MatchString(id) {
+					t.Fatal("expected id matching", g.format, "but got", id)
+				}
+				if _, ok := seen[id]; ok {
+					t.Fatal("expected unique ids but got duplicated", id)
+				}
+				seen[id] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestTimeOrderedGenerators(t *testing.T) {
+	before := time.Now().UnixMilli()
+
+	v7 := UUIDv7Generator()
+	ms, err := strconv.ParseInt(strings.ReplaceAll(v7[:13], "-", ""), 16, 64)
+	if err != nil || ms < before || ms > time.Now().UnixMilli() {
+		t.Fatal("expected uuidv7 starting with current unix milliseconds but got", v7, ms, err)
+	}
+
+	ulid := ULIDGenerator()
+	ms = 0
+	for _, c := range ulid[:10] {
+		ms = ms<<5 | int64(strings.IndexRune(crockfordBase32, c))
+	}
+	if ms < before || ms > time.Now().UnixMilli() {
+		t.Fatal("expected ulid starting with current unix milliseconds but got", ulid, ms)
+	}
+}
+
+func TestRandomGeneratorPanics(t *testing.T) {
+	cases := []struct {
+		length   int
+		alphabet string
+	}{
+		{0, ""},
+		{-1, "ab"},
+		{8, "a"},
+		{8, strings.Repeat("a", 257)},
+	}
+
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected RandomGenerator panic to length", c.length, "and alphabet of", len(c.alphabet), "chars")
+				}
+			}()
+
+			RandomGenerator(c.length, c.alphabet)
+		}()
+	}
+}
+
+func TestMiddlewareRequestIDGenerator(t *testing.T) {
+	useLogger(t, newTestLogger(new(strings.Builder)))
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		EnableRequestID:    true,
+		RequestIDGenerator: func() string { return "custom-id" },
+	}))
+	e.GET("/", okHandler)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+	if id := rec.Header().Get(echo.HeaderXRequestID); id != "custom-id" {
+		t.Fatal("expected generated request id custom-id but got", id)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "incoming")
+	rec = serve(e, req)
+	if id := rec.Header().Get(echo.HeaderXRequestID); id != "incoming" {
+		t.Fatal("expected incoming request id kept but got", id)
+	}
+}
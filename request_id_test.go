@@ -0,0 +1,127 @@
+package logecho
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCorrelationHeaders(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:             newTestLogger(b),
+		EnableRequestID:    true,
+		RequestIDGenerator: func() string { return "generated" },
+		RequestIDHeader: CorrelationHeader{
+			Name:      "X-Correlation-ID",
+			Fallbacks: []string{"X-Amzn-Trace-Id"},
+			MaxLength: 16,
+		},
+		TransactionIDHeader: CorrelationHeader{
+			Name:    "X-Tx",
+			Charset: "0123456789",
+		},
+		Fields: Fields{
+			"rid": RequestID,
+			"tid": TransactionID,
+		},
+	}))
+	e.GET("/", okHandler)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		rid     string
+		tid     string
+	}{
+		{"header", map[string]string{"X-Correlation-ID": "abc-123", "X-Tx": "42"}, "abc-123", "42"},
+		{"fallback", map[string]string{"X-Amzn-Trace-Id": "root:1"}, "root:1", ""},
+		{"too long", map[string]string{"X-Correlation-ID": strings.Repeat("a", 17)}, "generated", ""},
+		{"newline", map[string]string{"X-Correlation-ID": "a\nforged"}, "generated", ""},
+		{"charset", map[string]string{"X-Tx": "abc"}, "generated", ""},
+		{"default header ignored", map[string]string{echo.HeaderXRequestID: "old"}, "generated", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				req.Header[http.CanonicalHeaderKey(k)] = []string{v}
+			}
+			rec := serve(e, req)
+
+			if rid := rec.Header().Get("X-Correlation-ID"); rid != tc.rid {
+				t.Fatal("expected request id", tc.rid, "but got", rid)
+			}
+			if rec.Header().Get(echo.HeaderXRequestID) != "" {
+				t.Fatal("expected no default request id header but got", rec.Header().Get(echo.HeaderXRequestID))
+			}
+
+			// an empty tc.tid expects a generated transaction id
+			tid := rec.Header().Get("X-Tx")
+			switch {
+			case tc.tid != "" && tid != tc.tid:
+				t.Fatal("expected transaction id", tc.tid, "but got", tid)
+			case tc.tid == "" && (tid == "" || tid == tc.headers["X-Tx"]):
+				t.Fatal("expected generated transaction id but got", tid)
+			}
+
+			lines := decodeLines(t, b)
+			if len(lines) != 1 || lines[0]["rid"] != tc.rid || lines[0]["tid"] != tid {
+				t.Fatal("expected the installed ids on fields but got", lines)
+			}
+		})
+	}
+}
+
+func TestCorrelationIgnoreIncoming(t *testing.T) {
+	useLogger(t, newTestLogger(new(bytes.Buffer)))
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		EnableRequestID:     true,
+		RequestIDHeader:     CorrelationHeader{IgnoreIncoming: true},
+		TransactionIDHeader: CorrelationHeader{IgnoreIncoming: true},
+	}))
+	e.GET("/", okHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-id")
+	req.Header.Set("transaction-id", "client-tx")
+	rec := serve(e, req)
+
+	if id := rec.Header().Get(echo.HeaderXRequestID); id == "" || id == "client-id" {
+		t.Fatal("expected incoming request id replaced but got", id)
+	}
+	if id := rec.Header().Get("x-transaction-id"); id == "" || id == "client-tx" {
+		t.Fatal("expected incoming transaction id replaced but got", id)
+	}
+}
+
+func TestCorrelationInvalidConfig(t *testing.T) {
+	headers := []CorrelationHeader{
+		{Name: "X Bad"},
+		{Name: "X-Ok", Fallbacks: []string{""}},
+		{MaxLength: -1},
+	}
+
+	for _, h := range headers {
+		t.Run("should panic on invalid correlation header", func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected MiddlewareWithConfig panic to", h)
+				}
+			}()
+
+			MiddlewareWithConfig(MiddlewareConfig{RequestIDHeader: h})
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package logecho
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation sets the rotation of a file Output.
+//
+// Files are rotated when they would pass MaxSize. The rotated file
+// is renamed with its rotation timestamp, like
+//
+//	app-2006-01-02T15-04-05.000.log
+//
+// and backups are removed by MaxBackups and MaxAge
+type Rotation struct {
+	// MaxSize is the maximum size in bytes of the file before it
+	// gets rotated.
+	//
+	// Default is 0, who never rotates by size
+	MaxSize int64
+
+	// MaxAge is the maximum age of backups. Older backups are
+	// removed on rotation.
+	//
+	// Default is 0, who never removes backups by age
+	MaxAge time.Duration
+
+	// MaxBackups is the maximum number of backups to keep. The
+	// oldest backups are removed on rotation.
+	//
+	// Default is 0, who keeps all backups
+	MaxBackups int
+
+	// Compress enables gzip compression of backups
+	Compress bool
+
+	// ReopenOnSIGHUP reopens the file when the process receives
+	// SIGHUP, so external tools like logrotate can move the file.
+	//
+	// It has no effect on platforms without SIGHUP
+	ReopenOnSIGHUP bool
+}
+
+const (
+	// backupTimeFormat is the timestamp format on backup names
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	// compressSuffix is the suffix of compressed backups
+	compressSuffix = ".gz"
+)
+
+// RotatingFile is an io.Writer to a file who rotates by Rotation.
+//
+// It is safe for concurrent use. Backups are compressed and removed
+// in background, so writes never wait for them.
+type RotatingFile struct {
+	filename string
+	rotation Rotation
+
+	// now is the clock to backup timestamps and ages
+	now func() time.Time
+
+	m      sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	// millM runs a single mill at a time, mills waits pending mills
+	millM sync.Mutex
+	mills sync.WaitGroup
+
+	stopSignal func()
+}
+
+// ErrRotatingFileClosed is returned by writes after Close
+var ErrRotatingFileClosed = errors.New("logecho: rotating file is closed")
+
+// NewRotatingFile returns a RotatingFile to filename. The file is
+// opened on first write.
+//
+// Example:
+//
+//	logecho.Output{Writer: logecho.NewRotatingFile("/var/log/app.log", logecho.Rotation{
+//		MaxSize:    100 << 20, // 100MB
+//		MaxAge:     7 * 24 * time.Hour,
+//		MaxBackups: 10,
+//		Compress:   true,
+//	})}
+func NewRotatingFile(filename string, rotation Rotation) *RotatingFile {
+	r := &RotatingFile{
+		filename: filename,
+		rotation: rotation,
+		now:      time.Now,
+	}
+
+	if rotation.ReopenOnSIGHUP {
+		r.stopSignal = notifyReopen(r)
+	}
+
+	return r
+}
+
+// Write writes p to the file, rotating it before when p would pass
+// MaxSize. It fails with ErrRotatingFileClosed after Close
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if err := r.ensureOpen(); err != nil {
+		return 0, err
+	}
+
+	if r.rotation.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.rotation.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	return n, err
+}
+
+// Sync commits the file content to disk
+func (r *RotatingFile) Sync() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Sync()
+}
+
+// Rotate rotates the file now
+func (r *RotatingFile) Rotate() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.closed {
+		return ErrRotatingFileClosed
+	}
+
+	return r.rotate()
+}
+
+// Reopen closes and opens the file again, without rotating it.
+//
+// It is what logrotate expects after it moves the file
+func (r *RotatingFile) Reopen() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.closed {
+		return ErrRotatingFileClosed
+	}
+
+	if err := r.close(); err != nil {
+		return err
+	}
+
+	return r.open()
+}
+
+// Close closes the file and waits background compression and
+// removal of backups
+func (r *RotatingFile) Close() error {
+	if r.stopSignal != nil {
+		r.stopSignal()
+	}
+
+	r.m.Lock()
+	r.closed = true
+	err := r.close()
+	r.m.Unlock()
+
+	r.mills.Wait()
+
+	return err
+}
+
+// openNow opens the file now instead of on first write, so open
+// errors are reported on set up
+func (r *RotatingFile) openNow() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.ensureOpen()
+}
+
+// ensureOpen opens the file when it is not open yet. The lock must
+// be held
+func (r *RotatingFile) ensureOpen() error {
+	if r.closed {
+		return ErrRotatingFileClosed
+	}
+
+	if r.file != nil {
+		return nil
+	}
+
+	return r.open()
+}
+
+// open opens the file to append, creating it and its dir when
+// missing
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), 0o755); err != nil {
+		return fmt.Errorf("logecho: create log dir: %w", err)
+	}
+
+	file, err := os.OpenFile(r.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("logecho: open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("logecho: stat log file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+
+	return nil
+}
+
+func (r *RotatingFile) close() error {
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	r.size = 0
+
+	return err
+}
+
+// rotate renames the file to a backup, opens a new one and mills
+// the backups in background
+func (r *RotatingFile) rotate() error {
+	if err := r.close(); err != nil {
+		return err
+	}
+
+	err := os.Rename(r.filename, r.backupName(r.now()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("logecho: rotate log file: %w", err)
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.mills.Add(1)
+	go func() {
+		defer r.mills.Done()
+		r.mill()
+	}()
+
+	return nil
+}
+
+// backupName builds the backup file name to t
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// nameParts splits filename in dir, backup prefix and extension
+func (r *RotatingFile) nameParts() (string, string, string) {
+	dir, base := filepath.Split(r.filename)
+	ext := filepath.Ext(base)
+
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backup is a rotated file
+type backup struct {
+	path string
+	at   time.Time
+}
+
+// backups lists the rotated files, newest first
+func (r *RotatingFile) backups() ([]backup, error) {
+	dir, prefix, ext := r.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, compressSuffix), ext)
+		at, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix))
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backup{filepath.Join(dir, name), at})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+
+	return backups, nil
+}
+
+// mill removes backups by MaxBackups and MaxAge and compresses the
+// remaining when Compress is enabled
+func (r *RotatingFile) mill() {
+	r.millM.Lock()
+	defer r.millM.Unlock()
+
+	backups, err := r.backups()
+	if err != nil {
+		return
+	}
+
+	cutoff := r.now().Add(-r.rotation.MaxAge)
+	for i, b := range backups {
+		expired := r.rotation.MaxAge > 0 && b.at.Before(cutoff)
+		exceeded := r.rotation.MaxBackups > 0 && i >= r.rotation.MaxBackups
+
+		if expired || exceeded {
+			os.Remove(b.path)
+			continue
+		}
+
+		if r.rotation.Compress && !strings.HasSuffix(b.path, compressSuffix) {
+			compressFile(b.path)
+		}
+	}
+}
+
+// compressFile compresses path into path.gz and removes path
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(path + compressSuffix)
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package logecho
+
+// notifyReopen does nothing on platforms without SIGHUP
+func notifyReopen(r *RotatingFile) func() {
+	return func() {}
+}
@@ -0,0 +1,264 @@
+package logecho
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock who only moves when advanced
+type fakeClock struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.m.Lock()
+	f.now = f.now.Add(d)
+	f.m.Unlock()
+}
+
+func newTestRotatingFile(t *testing.T, rotation Rotation) (*RotatingFile, *fakeClock, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	clock := newFakeClock()
+
+	r := NewRotatingFile(filepath.Join(dir, "app.log"), rotation)
+	r.now = clock.Now
+	t.Cleanup(func() { r.Close() })
+
+	return r, clock, dir
+}
+
+// listDir returns the sorted file names in dir
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// readLogFile reads path, decompressing it when gzipped
+func readLogFile(t *testing.T, path string) string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, compressSuffix) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Run("should rotate by size", func(t *testing.T) {
+		r, clock, dir := newTestRotatingFile(t, Rotation{MaxSize: 10})
+
+		r.Write([]byte("first\n"))
+		clock.Advance(time.Second)
+		r.Write([]byte("second\n"))
+		r.Close()
+
+		expected := []string{"app-2023-01-02T03-04-06.000.log", "app.log"}
+		if names := listDir(t, dir); fmt.Sprint(names) != fmt.Sprint(expected) {
+			t.Fatal("expected files", expected, "but got", names)
+		}
+
+		if content := readLogFile(t, filepath.Join(dir, expected[0])); content != "first\n" {
+			t.Fatal("expected first line on backup but got", content)
+		}
+		if content := readLogFile(t, filepath.Join(dir, "app.log")); content != "second\n" {
+			t.Fatal("expected second line on file but got", content)
+		}
+	})
+
+	t.Run("should keep MaxBackups compressed", func(t *testing.T) {
+		r, clock, dir := newTestRotatingFile(t, Rotation{MaxBackups: 2, Compress: true})
+
+		for i := 0; i < 4; i++ {
+			r.Write([]byte(fmt.Sprintf("line %d\n", i)))
+			clock.Advance(time.Minute)
+			r.Rotate()
+		}
+		r.Close()
+
+		expected := []string{
+			"app-2023-01-02T03-07-05.000.log.gz",
+			"app-2023-01-02T03-08-05.000.log.gz",
+			"app.log",
+		}
+		if names := listDir(t, dir); fmt.Sprint(names) != fmt.Sprint(expected) {
+			t.Fatal("expected files", expected, "but got", names)
+		}
+
+		if content := readLogFile(t, filepath.Join(dir, expected[1])); content != "line 3\n" {
+			t.Fatal("expected last line on newest backup but got", content)
+		}
+	})
+
+	t.Run("should remove backups older than MaxAge", func(t *testing.T) {
+		r, clock, dir := newTestRotatingFile(t, Rotation{MaxAge: 24 * time.Hour})
+
+		r.Write([]byte("old\n"))
+		r.Rotate()
+		r.mills.Wait()
+
+		clock.Advance(25 * time.Hour)
+		r.Write([]byte("new\n"))
+		r.Rotate()
+		r.Close()
+
+		expected := []string{"app-2023-01-03T04-04-05.000.log", "app.log"}
+		if names := listDir(t, dir); fmt.Sprint(names) != fmt.Sprint(expected) {
+			t.Fatal("expected files", expected, "but got", names)
+		}
+	})
+
+	t.Run("should reopen moved file", func(t *testing.T) {
+		r, _, dir := newTestRotatingFile(t, Rotation{})
+
+		r.Write([]byte("before\n"))
+		os.Rename(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1"))
+		r.Write([]byte("still moved\n"))
+
+		if err := r.Reopen(); err != nil {
+			t.Fatal("expected no error but got", err)
+		}
+		r.Write([]byte("after\n"))
+		r.Close()
+
+		if content := readLogFile(t, filepath.Join(dir, "app.log.1")); content != "before\nstill moved\n" {
+			t.Fatal("expected writes before reopen on moved file but got", content)
+		}
+		if content := readLogFile(t, filepath.Join(dir, "app.log")); content != "after\n" {
+			t.Fatal("expected writes after reopen on new file but got", content)
+		}
+	})
+
+	t.Run("should keep every line on concurrent writes", func(t *testing.T) {
+		r, clock, dir := newTestRotatingFile(t, Rotation{MaxSize: 512, Compress: true})
+
+		const writers, lines = 8, 200
+
+		wg := sync.WaitGroup{}
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < lines; i++ {
+					clock.Advance(time.Millisecond)
+					r.Write([]byte(fmt.Sprintf("writer %d line %d\n", w, i)))
+				}
+			}(w)
+		}
+		wg.Wait()
+		r.Close()
+
+		seen := map[string]bool{}
+		for _, name := range listDir(t, dir) {
+			scanner := bufio.NewScanner(strings.NewReader(readLogFile(t, filepath.Join(dir, name))))
+			for scanner.Scan() {
+				seen[scanner.Text()] = true
+			}
+		}
+
+		if len(seen) != writers*lines {
+			t.Fatal("expected", writers*lines, "lines but got", len(seen))
+		}
+	})
+}
+
+func TestRotationOutput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	z, err := New(Config{Outputs: []Output{{Path: file, Rotation: &Rotation{MaxSize: 1 << 20}}}})
+	if err != nil {
+		t.Fatal("expected no error but got", err)
+	}
+
+	z.NoContext().Info("rotating output")
+	z.Close()
+
+	if lines := decodeLines(t, bytes.NewBufferString(readLogFile(t, file))); len(lines) != 1 {
+		t.Fatal("expected 1 log line but got", len(lines))
+	}
+
+	if _, err := New(Config{Outputs: []Output{{Path: Stdout, Rotation: &Rotation{}}}}); err == nil {
+		t.Fatal("expected error to Rotation without file")
+	}
+
+	// a file can not be a log dir
+	bad := filepath.Join(file, "app.log")
+	if _, err := New(Config{Outputs: []Output{{Path: bad, Rotation: &Rotation{}}}}); err == nil {
+		t.Fatal("expected open error to Rotation on", bad)
+	}
+}
+
+func TestRotatingFileWriteAfterClose(t *testing.T) {
+	r, _, dir := newTestRotatingFile(t, Rotation{})
+
+	if _, err := r.Write([]byte("before close\n")); err != nil {
+		t.Fatal("expected no error but got", err)
+	}
+	r.Close()
+	os.Remove(filepath.Join(dir, "app.log"))
+
+	if _, err := r.Write([]byte("after close\n")); err != ErrRotatingFileClosed {
+		t.Fatal("expected ErrRotatingFileClosed but got", err)
+	}
+	if err := r.Rotate(); err != ErrRotatingFileClosed {
+		t.Fatal("expected ErrRotatingFileClosed on Rotate but got", err)
+	}
+	if err := r.Reopen(); err != ErrRotatingFileClosed {
+		t.Fatal("expected ErrRotatingFileClosed on Reopen but got", err)
+	}
+
+	if files := listDir(t, dir); len(files) != 0 {
+		t.Fatal("expected no file reopened after close but got", files)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !windows && !plan9
+
+package logecho
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSIGHUP(t *testing.T) {
+	r, _, dir := newTestRotatingFile(t, Rotation{ReopenOnSIGHUP: true})
+
+	r.Write([]byte("before\n"))
+	os.Rename(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1"))
+
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "app.log")); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected file reopened on SIGHUP")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	r.Write([]byte("after\n"))
+	r.Close()
+
+	if content := readLogFile(t, filepath.Join(dir, "app.log")); content != "after\n" {
+		t.Fatal("expected writes after SIGHUP on new file but got", content)
+	}
+}
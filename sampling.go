@@ -0,0 +1,347 @@
+package logecho
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sampling keeps only part of the logs.
+//
+// The zero value keeps every log. When both are set, a log must
+// pass the counting and the Rate to be kept
+type Sampling struct {
+	// Rate is the fixed fraction of logs who are kept, from 0 to 1.
+	//
+	// Default is 0, who disables the fixed rate
+	Rate float64
+
+	// First logs are kept on each Tick, by key. After them only
+	// every Thereafter log is kept until the Tick ends.
+	//
+	// Application logs are keyed by level and message. Access logs
+	// are keyed by method and route
+	First int
+	// Thereafter is the interval of logs kept after First. Default
+	// is 0, who drops every log after First
+	Thereafter int
+	// Tick is the interval First and Thereafter are counted.
+	//
+	// Default is 1 second
+	Tick time.Duration
+
+	// ReportInterval is the interval how many logs were sampled out
+	// gets reported with a "sampled out logs" log.
+	//
+	// Default is 0, who never reports
+	ReportInterval time.Duration
+}
+
+// SamplingRule samples the access logs who match it.
+//
+// A rule without conditions matches every request. The zero Sampling
+// keeps every matched request
+type SamplingRule struct {
+	// MinStatus and MaxStatus is the status range the rule matches.
+	// Zero is unbounded
+	MinStatus int
+	MaxStatus int
+
+	// MinLatency matches requests who took MinLatency or more. Zero
+	// matches any latency
+	MinLatency time.Duration
+
+	// Sampling is applied to the matched requests
+	Sampling Sampling
+}
+
+// AccessSampling samples the "handled request" logs of the middleware.
+//
+// Example, keep every error and slow request and only 10% of the
+// remaining:
+//
+//	logecho.AccessSampling{
+//		Sampling: logecho.Sampling{Rate: 0.1, ReportInterval: time.Minute},
+//		Rules: []logecho.SamplingRule{
+//			{MinStatus: 400},
+//			{MinLatency: time.Second},
+//		},
+//	}
+type AccessSampling struct {
+	// Sampling is applied to requests who match no rule. Its
+	// ReportInterval reports the logs sampled out by every rule
+	Sampling
+
+	// Rules are checked in order. The first matched rule samples
+	// the request
+	Rules []SamplingRule
+}
+
+const (
+	defaultSamplingTick = time.Second
+	// samplingSlots is the number of counters keys are hashed into
+	samplingSlots = 4096
+	// sampledOutMessage is the message of sampled out reports
+	sampledOutMessage = "sampled out logs"
+)
+
+// getTick returns 1 second as default when Tick is not set
+func (s Sampling) getTick() time.Duration {
+	if s.Tick <= 0 {
+		return defaultSamplingTick
+	}
+
+	return s.Tick
+}
+
+// validate returns the problems of s, named by name
+func (s Sampling) validate(name string) []string {
+	var problems []string
+
+	if s.Rate < 0 || s.Rate > 1 {
+		problems = append(problems, name+" Rate must be between 0 and 1")
+	}
+
+	if s.First < 0 || s.Thereafter < 0 {
+		problems = append(problems, name+" First and Thereafter must not be negative")
+	}
+
+	return problems
+}
+
+// sampleCounter counts logs of a key on the current tick
+type sampleCounter struct {
+	resetAt int64
+	n       uint64
+}
+
+// incr counts a log at now and returns the count on the tick
+func (c *sampleCounter) incr(now, tick int64) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if now > resetAt && atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+tick) {
+		atomic.StoreUint64(&c.n, 1)
+		return 1
+	}
+
+	return atomic.AddUint64(&c.n, 1)
+}
+
+// sampler decides which logs are kept by Sampling. It is safe for
+// concurrent use without locks
+type sampler struct {
+	cfg      Sampling
+	counters []sampleCounter
+	now      func() time.Time
+
+	dropped    uint64
+	nextReport int64
+}
+
+func newSampler(cfg Sampling) *sampler {
+	s := &sampler{cfg: cfg, now: time.Now}
+	if cfg.First > 0 || cfg.Thereafter > 0 {
+		s.counters = make([]sampleCounter, samplingSlots)
+	}
+
+	return s
+}
+
+// randPool holds seeded rand sources, so sampling does not lock
+// the math/rand global source
+var randPool = sync.Pool{
+	New: func() interface{} {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	},
+}
+
+// sample decides if the log of key is kept
+func (s *sampler) sample(key string) bool {
+	keep := true
+
+	if s.counters != nil {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+
+		n := s.counters[h.Sum32()%samplingSlots].incr(s.now().UnixNano(), int64(s.cfg.getTick()))
+		first := uint64(s.cfg.First)
+		keep = n <= first || (s.cfg.Thereafter > 0 && (n-first)%uint64(s.cfg.Thereafter) == 0)
+	}
+
+	if keep && s.cfg.Rate > 0 && s.cfg.Rate < 1 {
+		rnd := randPool.Get().(*rand.Rand)
+		keep = rnd.Float64() < s.cfg.Rate
+		randPool.Put(rnd)
+	}
+
+	if !keep {
+		atomic.AddUint64(&s.dropped, 1)
+	}
+
+	return keep
+}
+
+// due checks if a report of sampled out logs is due
+func (s *sampler) due() bool {
+	if s.cfg.ReportInterval <= 0 {
+		return false
+	}
+
+	now := s.now().UnixNano()
+	next := atomic.LoadInt64(&s.nextReport)
+
+	return now >= next && atomic.CompareAndSwapInt64(&s.nextReport, next, now+int64(s.cfg.ReportInterval))
+}
+
+// takeDropped returns how many logs were sampled out since the last
+// call
+func (s *sampler) takeDropped() uint64 {
+	return atomic.SwapUint64(&s.dropped, 0)
+}
+
+// reportFields are the fields of a sampled out report
+func (s *sampler) reportFields(n uint64) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint64("sampled_out", n),
+		zap.Duration("interval", s.cfg.ReportInterval),
+	}
+}
+
+// samplingCore samples the application logs of Config Sampling.
+//
+// Logs at Error level and above are never sampled out
+type samplingCore struct {
+	zapcore.Core
+	s *sampler
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{c.Core.With(fields), c.s}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	if c.s.due() {
+		if n := c.s.takeDropped(); n > 0 {
+			// the report goes through Check, so each core of a Tee
+			// only writes it when INFO is enabled to that core
+			report := zapcore.Entry{
+				Level:   zapcore.InfoLevel,
+				Time:    ent.Time,
+				Message: sampledOutMessage,
+			}
+			if rce := c.Core.Check(report, nil); rce != nil {
+				rce.Write(c.s.reportFields(n)...)
+			}
+		}
+	}
+
+	if ent.Level < zapcore.ErrorLevel && !c.s.sample(ent.Level.String()+ent.Message) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+// accessSampler samples access logs by AccessSampling
+type accessSampler struct {
+	rules    []SamplingRule
+	samplers []*sampler
+	fallback *sampler
+}
+
+// newAccessSampler compiles cfg. It panics when cfg is invalid, as it
+// is a set up mistake
+func newAccessSampler(cfg *AccessSampling) *accessSampler {
+	if cfg == nil {
+		return nil
+	}
+
+	if problems := cfg.validate(); len(problems) > 0 {
+		panic("logecho: invalid Sampling: " + strings.Join(problems, "; "))
+	}
+
+	a := &accessSampler{
+		rules:    cfg.Rules,
+		samplers: make([]*sampler, len(cfg.Rules)),
+		fallback: newSampler(cfg.Sampling),
+	}
+
+	for i, rule := range cfg.Rules {
+		a.samplers[i] = newSampler(rule.Sampling)
+	}
+
+	return a
+}
+
+// validate returns the problems of the fallback Sampling and of each
+// rule
+func (cfg *AccessSampling) validate() []string {
+	problems := cfg.Sampling.validate("Sampling")
+	for i, rule := range cfg.Rules {
+		problems = append(problems, rule.Sampling.validate("Rules["+strconv.Itoa(i)+"]")...)
+	}
+
+	return problems
+}
+
+// match checks if the request status and latency match the rule
+func (r SamplingRule) match(status int, latency time.Duration) bool {
+	if r.MinStatus > 0 && status < r.MinStatus {
+		return false
+	}
+
+	if r.MaxStatus > 0 && status > r.MaxStatus {
+		return false
+	}
+
+	return latency >= r.MinLatency
+}
+
+// sample decides if the access log of the request is kept. A nil
+// accessSampler keeps every log
+func (a *accessSampler) sample(c echo.Context, latency time.Duration) bool {
+	if a == nil {
+		return true
+	}
+
+	key := c.Request().Method + " " + c.Path()
+	for i, rule := range a.rules {
+		if rule.match(c.Response().Status, latency) {
+			return a.samplers[i].sample(key)
+		}
+	}
+
+	return a.fallback.sample(key)
+}
+
+// report logs how many access logs were sampled out when a report
+// is due
+func (a *accessSampler) report(l *Logecho) {
+	if a == nil {
+		return
+	}
+
+	if !a.fallback.due() {
+		return
+	}
+
+	n := a.fallback.takeDropped()
+	for _, s := range a.samplers {
+		n += s.takeDropped()
+	}
+
+	if n > 0 {
+		l.zl.Info(sampledOutMessage, a.fallback.reportFields(n)...)
+	}
+}
@@ -0,0 +1,239 @@
+package logecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSampler(t *testing.T) {
+	t.Run("should keep first then every thereafter on each tick", func(t *testing.T) {
+		clock := newFakeClock()
+		s := newSampler(Sampling{First: 2, Thereafter: 3, Tick: time.Second})
+		s.now = clock.Now
+
+		var kept []int
+		for i := 1; i <= 10; i++ {
+			if s.sample("key") {
+				kept = append(kept, i)
+			}
+		}
+
+		if len(kept) != 4 || kept[2] != 5 || kept[3] != 8 {
+			t.Fatal("expected logs 1, 2, 5 and 8 kept but got", kept)
+		}
+
+		if !s.sample("other") {
+			t.Fatal("expected other key counted apart")
+		}
+
+		clock.Advance(2 * time.Second)
+		if !s.sample("key") {
+			t.Fatal("expected counter reset on next tick")
+		}
+
+		if dropped := s.takeDropped(); dropped != 6 {
+			t.Fatal("expected 6 dropped but got", dropped)
+		}
+	})
+
+	t.Run("should keep a fixed rate", func(t *testing.T) {
+		s := newSampler(Sampling{Rate: 0.25})
+
+		kept := 0
+		for i := 0; i < 4000; i++ {
+			if s.sample("key") {
+				kept++
+			}
+		}
+
+		if kept < 800 || kept > 1200 {
+			t.Fatal("expected about 1000 logs kept but got", kept)
+		}
+	})
+
+	t.Run("should report on interval", func(t *testing.T) {
+		clock := newFakeClock()
+		s := newSampler(Sampling{ReportInterval: time.Minute})
+		s.now = clock.Now
+
+		if !s.due() {
+			t.Fatal("expected first report due")
+		}
+		if s.due() {
+			t.Fatal("expected no report before interval")
+		}
+
+		clock.Advance(time.Minute)
+		if !s.due() {
+			t.Fatal("expected report due after interval")
+		}
+	})
+}
+
+func TestSamplingConfig(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	z, err := New(Config{
+		Outputs:  []Output{{Writer: b}},
+		Sampling: &Sampling{First: 1, ReportInterval: time.Minute},
+	})
+	if err != nil {
+		t.Fatal("expected no error but got", err)
+	}
+
+	clock := newFakeClock()
+	z.zl.Core().(*samplingCore).s.now = clock.Now
+
+	l := z.NoContext()
+	for i := 0; i < 3; i++ {
+		l.Info("same message")
+		l.Error("error message")
+	}
+
+	clock.Advance(time.Minute)
+	l.Info("other message")
+
+	lines := decodeLines(t, b)
+	if len(lines) != 6 {
+		t.Fatal("expected 6 log lines but got", len(lines))
+	}
+
+	report := lines[4]
+	if report["message"] != sampledOutMessage || report["sampled_out"] != json.Number("2") {
+		t.Fatal("expected report of 2 sampled out logs but got", report)
+	}
+
+	if _, err := New(Config{Sampling: &Sampling{Rate: 2, First: -1}}); err == nil {
+		t.Fatal("expected error to invalid Sampling")
+	}
+}
+
+func TestSamplingReportLevel(t *testing.T) {
+	all, errors := new(bytes.Buffer), new(bytes.Buffer)
+
+	z, err := New(Config{
+		Outputs: []Output{
+			{Writer: all},
+			{Writer: errors, Level: zapcore.ErrorLevel},
+		},
+		Sampling: &Sampling{First: 1, ReportInterval: time.Minute},
+	})
+	if err != nil {
+		t.Fatal("expected no error but got", err)
+	}
+
+	clock := newFakeClock()
+	z.zl.Core().(*samplingCore).s.now = clock.Now
+
+	l := z.NoContext()
+	l.Info("same message")
+	l.Info("same message")
+
+	clock.Advance(time.Minute)
+	l.Error("error message")
+
+	if lines := decodeLines(t, errors); len(lines) != 1 || lines[0]["message"] != "error message" {
+		t.Fatal("expected only the error log on the ERROR output but got", lines)
+	}
+
+	lines := decodeLines(t, all)
+	if len(lines) != 3 || lines[1]["message"] != sampledOutMessage {
+		t.Fatal("expected the report on the INFO output but got", lines)
+	}
+}
+
+func TestAccessSampling(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger: l,
+		Fields: Fields{"status": Status},
+		Sampling: &AccessSampling{
+			Sampling: Sampling{First: 1},
+			Rules:    []SamplingRule{{MinStatus: 500}},
+		},
+	}))
+	e.GET("/ok", okHandler)
+	e.GET("/other", okHandler)
+	e.GET("/fail", func(c echo.Context) error {
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	for i := 0; i < 3; i++ {
+		serve(e, httptest.NewRequest(http.MethodGet, "/ok", nil))
+		serve(e, httptest.NewRequest(http.MethodGet, "/other", nil))
+		serve(e, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 5 {
+		t.Fatal("expected 5 log lines but got", len(lines))
+	}
+
+	kept := 0
+	for _, line := range lines {
+		if line["status"] == json.Number("500") {
+			kept++
+		}
+	}
+	if kept != 3 {
+		t.Fatal("expected every error kept but got", kept)
+	}
+}
+
+func TestAccessSamplerReport(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	clock := newFakeClock()
+	a := newAccessSampler(&AccessSampling{
+		Sampling: Sampling{First: 1, ReportInterval: time.Minute},
+		Rules:    []SamplingRule{{MinLatency: time.Second, Sampling: Sampling{First: 1}}},
+	})
+	a.fallback.now = clock.Now
+	a.samplers[0].now = clock.Now
+
+	c := NewContext()
+	a.report(l)
+	for i := 0; i < 3; i++ {
+		a.sample(c, time.Millisecond)
+		a.sample(c, 2*time.Second)
+	}
+
+	clock.Advance(time.Minute)
+	a.report(l)
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || lines[0]["msg"] != sampledOutMessage || lines[0]["sampled_out"] != json.Number("4") {
+		t.Fatal("expected report of 4 sampled out logs but got", lines)
+	}
+}
+
+func TestAccessSamplingValidation(t *testing.T) {
+	for _, sampling := range []*AccessSampling{
+		{Sampling: Sampling{Rate: 1.5}},
+		{Sampling: Sampling{Rate: -0.1}},
+		{Sampling: Sampling{First: -1}},
+		{Rules: []SamplingRule{{MinStatus: 500, Sampling: Sampling{Thereafter: -1}}}},
+	} {
+		t.Run("should panic on invalid Sampling", func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), "invalid Sampling") {
+					t.Fatal("expected panic with invalid Sampling but got", r)
+				}
+			}()
+
+			MiddlewareWithConfig(MiddlewareConfig{Sampling: sampling})
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package logecho
+
+import (
+	pathpkg "path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Skipper defines a function to skip logging a request. Returning
+// true skips it, like echo middleware Skipper
+type Skipper func(c echo.Context) bool
+
+// Exclusions declares requests who are not logged by the middleware.
+//
+// Example:
+//
+//	logecho.Exclusions{
+//		Methods: []string{http.MethodOptions},
+//		Paths:   []string{"/health", "/metrics"},
+//		Routes:  []string{"/debug/pprof/*"},
+//		Globs:   []string{"/static/*", "/*.ico"},
+//	}
+type Exclusions struct {
+	// Methods excludes requests by HTTP method
+	Methods []string
+
+	// Paths excludes requests by exact URL path
+	Paths []string
+
+	// Routes excludes requests by the route pattern who matched
+	// them, the same as c.Path(), like "/users/:id"
+	Routes []string
+
+	// Globs excludes requests who URL path matches any glob. Globs
+	// follow pathpkg.Match syntax, where * does not match /
+	Globs []string
+}
+
+// empty checks if e excludes nothing
+func (e Exclusions) empty() bool {
+	return len(e.Methods) == 0 && len(e.Paths) == 0 && len(e.Routes) == 0 && len(e.Globs) == 0
+}
+
+// skipper builds a Skipper who skips requests excluded
+// by e. It panics when a glob is malformed, like echo middlewares
+// do with invalid configs
+func (e Exclusions) skipper() Skipper {
+	for _, glob := range e.Globs {
+		if _, err := pathpkg.Match(glob, ""); err != nil {
+			panic("logecho: invalid Exclusions glob " + glob + ": " + err.Error())
+		}
+	}
+
+	methods := toSet(e.Methods, strings.ToUpper)
+	paths := toSet(e.Paths, nil)
+	routes := toSet(e.Routes, nil)
+
+	return func(c echo.Context) bool {
+		if methods[c.Request().Method] || routes[c.Path()] {
+			return true
+		}
+
+		urlPath := c.Request().URL.Path
+		if paths[urlPath] {
+			return true
+		}
+
+		for _, glob := range e.Globs {
+			if ok, _ := pathpkg.Match(glob, urlPath); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// toSet builds a set of values, normalized by normalize when it
+// is not nil
+func toSet(values []string, normalize func(string) string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		if normalize != nil {
+			value = normalize(value)
+		}
+		set[value] = true
+	}
+
+	return set
+}
+
+// skipper combines Skipper and Exclude of cfg. It returns nil when
+// no request is skipped
+func (cfg MiddlewareConfig) skipper() Skipper {
+	var skippers []Skipper
+	if cfg.Skipper != nil {
+		skippers = append(skippers, cfg.Skipper)
+	}
+
+	if !cfg.Exclude.empty() {
+		skippers = append(skippers, cfg.Exclude.skipper())
+	}
+
+	if len(skippers) == 0 {
+		return nil
+	}
+
+	return func(c echo.Context) bool {
+		for _, skip := range skippers {
+			if skip(c) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package logecho
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddlewareSkipper(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:          l,
+		EnableRequestID: true,
+		Fields:          Fields{"path": Path},
+		Skipper: func(c echo.Context) bool {
+			return c.Request().Header.Get("x-skip") != ""
+		},
+		Exclude: Exclusions{
+			Methods: []string{"options"},
+			Paths:   []string{"/health"},
+			Routes:  []string{"/users/:id/avatar"},
+			Globs:   []string{"/static/*.css"},
+		},
+	}))
+	e.Any("/*", okHandler)
+	e.GET("/users/:id/avatar", okHandler)
+
+	skipped := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/health", nil),
+		httptest.NewRequest(http.MethodOptions, "/users", nil),
+		httptest.NewRequest(http.MethodGet, "/users/10/avatar", nil),
+		httptest.NewRequest(http.MethodGet, "/static/app.css", nil),
+		httptest.NewRequest(http.MethodGet, "/users", nil),
+	}
+	skipped[4].Header.Set("x-skip", "1")
+
+	for _, req := range skipped {
+		rec := serve(e, req)
+		if rec.Header().Get(echo.HeaderXRequestID) == "" || rec.Header().Get("x-transaction-id") == "" {
+			t.Fatal("expected ids headers on skipped request", req.URL.Path)
+		}
+	}
+
+	if b.Len() != 0 {
+		t.Fatal("expected no log to skipped requests but got", b.String())
+	}
+
+	for _, target := range []string{"/health/deep", "/static/js/app.css", "/users"} {
+		serve(e, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	if lines := decodeLines(t, b); len(lines) != 3 {
+		t.Fatal("expected 3 log lines but got", len(lines))
+	}
+
+	t.Run("should panic on invalid glob", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil || !strings.Contains(r.(string), "[") {
+				t.Fatal("expected panic with glob but got", r)
+			}
+		}()
+
+		MiddlewareWithConfig(MiddlewareConfig{Exclude: Exclusions{Globs: []string{"["}}})
+	})
+}
+
+func TestSkippedRequestScope(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	var requestID, transactionID string
+	var logger *Logecho
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:          l,
+		EnableRequestID: true,
+		Exclude:         Exclusions{Paths: []string{"/health"}},
+	}))
+	e.GET("/health", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		requestID, transactionID = RequestIDFromContext(ctx), TransactionIDFromContext(ctx)
+		logger = FromEchoContext(c)
+		FromContext(ctx).Info("checking health")
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if requestID == "" || requestID != rec.Header().Get(echo.HeaderXRequestID) {
+		t.Fatal("expected request id from context on skipped request but got", requestID)
+	}
+	if transactionID == "" || transactionID != rec.Header().Get("x-transaction-id") {
+		t.Fatal("expected transaction id from context on skipped request but got", transactionID)
+	}
+	if logger != l {
+		t.Fatal("expected the middleware logger from echo.Context on skipped request but got", logger)
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || lines[0]["request_id"] != requestID {
+		t.Fatal("expected only the handler log with request id but got", lines)
+	}
+}
@@ -0,0 +1,107 @@
+package logecho
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestStartLog(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:          l,
+		EnableStartLog:  true,
+		EnableRequestID: true,
+		Fields: Fields{
+			"path":    Path,
+			"body":    Body,
+			"status":  Status,
+			"latency": LatencyInMs,
+		},
+	}))
+	e.POST("/echo", func(c echo.Context) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusCreated, string(body))
+	})
+
+	rec := serve(e, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	if rec.Body.String() != "hello" {
+		t.Fatal("expected handler to read the whole body but got", rec.Body.String())
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 2 {
+		t.Fatal("expected start and handled logs but got", lines)
+	}
+
+	start, handled := lines[0], lines[1]
+	if start["msg"] != "request started" || start["level"] != "info" {
+		t.Fatal("expected info request started log but got", start)
+	}
+	if start["path"] != "/echo" || start["body"] != "hello" {
+		t.Fatal("expected request fields on start log but got", start)
+	}
+	for _, key := range []string{"status", "latency"} {
+		if _, ok := start[key]; ok {
+			t.Fatal("expected no response field", key, "on start log but got", start)
+		}
+	}
+
+	if handled["msg"] != "handled request" || handled["status"] != jsonNumber(http.StatusCreated) {
+		t.Fatal("expected handled request log with status 201 but got", handled)
+	}
+
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	if id == "" || start["request_id"] != id || handled["request_id"] != id {
+		t.Fatal("expected logs paired by request id", id, "but got", start["request_id"], handled["request_id"])
+	}
+}
+
+func TestStartLogDisabled(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:          newTestLogger(b),
+		EnableRequestID: true,
+		StartMessage:    "begin",
+	}))
+	e.GET("/", okHandler)
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || lines[0]["msg"] != "handled request" {
+		t.Fatal("expected only the handled log but got", lines)
+	}
+	if _, ok := lines[0]["request_id"]; ok {
+		t.Fatal("expected handled log without request id but got", lines[0])
+	}
+}
+
+func TestStartLogMessage(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:         newTestLogger(b),
+		EnableStartLog: true,
+		StartMessage:   "begin",
+	}))
+	e.GET("/", okHandler)
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := decodeLines(t, b)
+	if len(lines) != 2 || lines[0]["msg"] != "begin" {
+		t.Fatal("expected custom start message but got", lines)
+	}
+}
@@ -2,15 +2,16 @@ package logecho
 
 import (
 	"net/url"
-	"os"
-	"text/template"
+	"reflect"
 
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // ContextFields is the accepted field to generate
-// a Fields template. It have two classes, Request fields and
-// Response fields.
+// a Fields template. Each built-in Field extracts one of them.
+// It have two classes, Request fields and Response fields.
 //
 // Request fields can not be present in case that is a optional field.
 //
@@ -19,6 +20,9 @@ import (
 //
 // A Response field who appears before a request response is Status.
 // That one will be 200 by default incoming from request defaults
+//
+// Deprecated: ContextFields is not built or read anymore, each Field
+// extracts its value straight from echo.Context
 type ContextFields struct {
 	// request fields
 
@@ -43,37 +47,147 @@ type ContextFields struct {
 	BytesOut int64 // BytesOut is Response class field. It can be wrong before request's response
 }
 
-func getTemplateFields(c echo.Context) ContextFields {
-	return ContextFields{
-		// request fields
-		RequestURI:      c.Request().RequestURI,
-		RequestID:       getXRequestID(c),
-		TransactionID:   getTransactionID(c),
-		RealIP:          c.RealIP(),
-		Host:            c.Request().Host,
-		Method:          c.Request().Method,
-		Referer:         c.Request().Referer(),
-		UserAgent:       c.Request().UserAgent(),
-		Query:           c.Request().URL.Query(),
-		Path:            c.Request().URL.Path,
-		UrlEncodedQuery: c.Request().URL.RawQuery,
-		BytesIn:         c.Request().Header.Get(echo.HeaderContentLength),
-		Body:            readBody(c),
-		Params:          string(buildParams(c)),
-		// response fields
-		Status:   c.Response().Status,
-		BytesOut: c.Response().Size,
+// stringField builds a Field who extracts a string from echo.Context
+func stringField(tpl string, fn func(c echo.Context) string) Field {
+	return Field{tpl: tpl, kind: reflect.String, extract: func(c echo.Context, key string) zapcore.Field {
+		return zap.String(key, fn(c))
+	}}
+}
+
+// intField builds a Field who extracts an int from echo.Context
+func intField(tpl string, fn func(c echo.Context) int) Field {
+	return Field{tpl: tpl, kind: reflect.Int, extract: func(c echo.Context, key string) zapcore.Field {
+		return zap.Int(key, fn(c))
+	}}
+}
+
+// int64Field builds a Field who extracts an int64 from echo.Context
+func int64Field(tpl string, fn func(c echo.Context) int64) Field {
+	return Field{tpl: tpl, kind: reflect.Int64, extract: func(c echo.Context, key string) zapcore.Field {
+		return zap.Int64(key, fn(c))
+	}}
+}
+
+// optionalBoolField builds a Field who extracts a bool from
+// echo.Context. It is skipped when fn is not ok
+func optionalBoolField(tpl string, fn func(c echo.Context) (bool, bool)) Field {
+	return Field{tpl: tpl, kind: reflect.Bool, extract: func(c echo.Context, key string) zapcore.Field {
+		value, ok := fn(c)
+		if !ok {
+			return zap.Skip()
+		}
+
+		return zap.Bool(key, value)
+	}}
+}
+
+// objectField builds a Field who extracts an object from echo.Context
+func objectField(tpl string, fn func(c echo.Context) zapcore.ObjectMarshaler) Field {
+	return Field{tpl: tpl, kind: reflect.Map, extract: func(c echo.Context, key string) zapcore.Field {
+		return zap.Object(key, fn(c))
+	}}
+}
+
+// latencyField builds a Latency Field to scale
+func latencyField(scale string, kind reflect.Kind) Field {
+	f := FuncFieldWithArgs(LatencyField, scale)
+	f.kind = kind
+
+	return responseClass(f)
+}
+
+// responseClass marks f as a Response class field, who is only
+// known after the handler responds
+func responseClass(f Field) Field {
+	f.response = true
+	return f
+}
+
+// stringFuncField adapts fn to a FuncField extract who results
+// in a string
+func stringFuncField(fn func(c echo.Context, args ...string) string) func(echo.Context, string, []string) zapcore.Field {
+	return func(c echo.Context, key string, args []string) zapcore.Field {
+		return zap.String(key, fn(c, args...))
+	}
+}
+
+// uint64FuncField adapts fn to a FuncField extract who results
+// in an uint64
+func uint64FuncField(fn func() uint64) func(echo.Context, string, []string) zapcore.Field {
+	return func(_ echo.Context, key string, _ []string) zapcore.Field {
+		return zap.Uint64(key, fn())
+	}
+}
+
+// latencyFuncField extracts the latency in the scale from first arg
+func latencyFuncField(c echo.Context, key string, args []string) zapcore.Field {
+	scale := ""
+	if len(args) > 0 {
+		scale = args[0]
+	}
+
+	stop := calcLatency(c)
+
+	switch scale {
+	case "s":
+		return zap.Float64(key, stop.Seconds())
+	case "ms":
+		return zap.Int64(key, stop.Milliseconds())
+	case "us":
+		return zap.Int64(key, stop.Microseconds())
+	case "ns":
+		return zap.Int64(key, stop.Nanoseconds())
+	case "duration":
+		return zap.Duration(key, stop)
+	default:
+		return zap.String(key, stop.String())
+	}
+}
+
+func requestURI(c echo.Context) string      { return c.Request().RequestURI }
+func realIP(c echo.Context) string          { return c.RealIP() }
+func host(c echo.Context) string            { return c.Request().Host }
+func method(c echo.Context) string          { return c.Request().Method }
+func referer(c echo.Context) string         { return c.Request().Referer() }
+func userAgent(c echo.Context) string       { return c.Request().UserAgent() }
+func path(c echo.Context) string            { return c.Request().URL.Path }
+func urlEncodedQuery(c echo.Context) string { return c.Request().URL.RawQuery }
+func bytesIn(c echo.Context) string         { return c.Request().Header.Get(echo.HeaderContentLength) }
+func status(c echo.Context) int             { return c.Response().Status }
+func bytesOut(c echo.Context) int64         { return c.Response().Size }
+
+func query(c echo.Context) zapcore.ObjectMarshaler {
+	return valuesObject(c.Request().URL.Query())
+}
+
+func params(c echo.Context) zapcore.ObjectMarshaler {
+	names := c.ParamNames()
+	values := make(valuesObject, len(names))
+	for _, name := range names {
+		values[name] = []string{c.Param(name)}
 	}
+
+	return values
 }
 
-func getTemplateFuncMap(c echo.Context) template.FuncMap {
-	return template.FuncMap{
-		string(ParamField.name):      c.Param,
-		string(HeaderField.name):     getHeader(c),
-		string(CookieField.name):     extractCookie(c),
-		string(LatencyField.name):    calcLatency(c),
-		string(GetenvField.name):     func(key string) string { return os.Getenv(key) },
-		string(RunningReqField.name): CurrentCount,
-		string(ConcurrentField.name): TransactionCounter,
+// valuesObject logs url.Values like an object. Single values
+// are logged as strings and multiple values as arrays
+type valuesObject url.Values
+
+func (v valuesObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for key, values := range v {
+		if len(values) == 1 {
+			enc.AddString(key, values[0])
+			continue
+		}
+
+		enc.AddArray(key, zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			for _, value := range values {
+				arr.AppendString(value)
+			}
+			return nil
+		}))
 	}
+
+	return nil
 }
@@ -1,134 +1,94 @@
 package logecho
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/url"
-	"reflect"
-	"sync"
-	"text/template"
+	"sort"
 
 	"github.com/labstack/echo/v4"
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var (
-	tpl       = new(template.Template)
-	poolMutex = new(sync.RWMutex)
-	buf       = new(bytes.Buffer)
-)
+// templateKey is the echo.Context key where the middleware
+// stores its compiled fields template
+const templateKey = "logecho.template"
 
-// readContext gets the buffer and execute template writing
-// result in a buffer.
+// fieldsTemplate is the Fields template compiled by a middleware
+// instance.
 //
-// Returns the result of the template execution in bytes
-func readContext(c echo.Context) []byte {
-	poolMutex.RLock()
-	buf := new(bytes.Buffer)
-	tpl.Execute(buf, getTemplateFields(c))
-	poolMutex.RUnlock()
-
-	return buf.Bytes()
+// It is built only once, when the middleware is built, and executed
+// on each log call. Each Field extracts its typed value straight
+// from echo.Context, so it needs no lock and no intermediate format.
+type fieldsTemplate struct {
+	keys   []string
+	fields []Field
+}
+
+// compileTemplate will read ctxFields were defined in MiddlewareConfig and
+// build the template in a stable key order
+func compileTemplate(ctxFields Fields) *fieldsTemplate {
+	keys := make([]string, 0, len(ctxFields))
+	for key := range ctxFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]Field, len(keys))
+	for i, key := range keys {
+		fields[i] = ctxFields[key]
+	}
+
+	return &fieldsTemplate{keys, fields}
 }
 
-// getFields will receive a slice of bytes who contains
-// bytes from template execution to build zapcore.Field slice.
-func getFields(fbytes []byte) []zapcore.Field {
-	// lock bytes and map work until that build
-	poolMutex.Lock()
-	defer poolMutex.Unlock()
-
-	// expect a json format of bytes to unmarshal
-	// into a map[string]interface{}
-	var f map[string]interface{}
-	json.Unmarshal(fbytes, &f)
-
-	fields := make([]zapcore.Field, 0, len(f))
-	for field, value := range f {
-		switch v := value.(type) {
-		// by default any number will income as float64
-		case float64:
-			fields = append(fields, zap.Float64(field, v))
-		case int64:
-			fields = append(fields, zap.Int64(field, v))
-		case int:
-			fields = append(fields, zap.Int(field, v))
-		case url.Values:
-			fields = append(fields, zap.String(field, v.Encode()))
-		case string:
-			fields = append(fields, zap.String(field, v))
-		default:
-			log.Println("[DEBUG] field", field, "was added as string but is ", reflect.TypeOf(v))
-			fields = append(fields, zap.String(field, fmt.Sprintf("%v", v)))
+// requestClass returns a template with only the Request class fields
+func (t *fieldsTemplate) requestClass() *fieldsTemplate {
+	req := &fieldsTemplate{}
+	for i, field := range t.fields {
+		if field.response {
 			continue
 		}
+
+		req.keys = append(req.keys, t.keys[i])
+		req.fields = append(req.fields, field)
+	}
+
+	return req
+}
+
+// execute extracts each template field from echo.Context
+func (t *fieldsTemplate) execute(c echo.Context) []zapcore.Field {
+	fields := make([]zapcore.Field, len(t.fields))
+	for i, field := range t.fields {
+		fields[i] = field.zapField(c, t.keys[i])
 	}
 
 	return fields
 }
 
-// configTemplate will read ctxFields were defined in MiddlewareConfig and
-// build the template format to write on log
-func configTemplate(ctxFields Fields, c echo.Context) {
-	// lock tpl work to avoid concurrent requests
-	poolMutex.Lock()
-	parseFields(ctxFields, buf)
-
-	tpl = template.Must(
-		template.New("fields").
-			Funcs(getTemplateFuncMap(c)).
-			Parse(buf.String()),
-	)
-	poolMutex.Unlock()
+// installTemplate sets the compiled template on the context, so any
+// log call with that context uses the template of the middleware who
+// handles the request
+func installTemplate(c echo.Context, t *fieldsTemplate) {
+	c.Set(templateKey, t)
 }
 
-// parseFields will receive Fields tpl config and write into a buffer
-// with JSON format
-func parseFields(f Fields, w *bytes.Buffer) {
-	w.Reset()
-
-	lastIndex := len(f)
-	indexCounter := 0
-
-	// Start string with {
-	w.WriteRune('{')
-	for key, value := range f {
-		indexCounter++
-
-		// at this point the buffer contains the string as:
-		// {"
-		w.WriteRune('"')
-		// {"keyValue
-		w.WriteString(key)
-		// {"keyValue":
-		w.WriteString(`":`)
-		// {"keyValue": "value"
-		writeField(w, value)
-
-		if indexCounter != lastIndex {
-			// when has more than one field adds ,
-			// and continue loop
-			// {"keyValue": "value",
-			w.WriteRune(',')
-		}
+// getTemplate recovers the compiled template from the context. It
+// returns nil when the request was not handled by the middleware
+func getTemplate(c echo.Context) *fieldsTemplate {
+	if t, ok := c.Get(templateKey).(*fieldsTemplate); ok {
+		return t
 	}
-	// {"keyValue": "value"}
-	w.WriteRune('}')
+
+	return nil
 }
 
-// writeField will check Type from field and write correctly on
-// buffer. To quoted fields write "{{ Tpl }}", to unquoted
-// fields write bytes as {{ Tpl }}
-func writeField(w *bytes.Buffer, f Field) {
-	switch f.Type() {
-	case reflect.Int, reflect.Int64, reflect.Bool:
-		w.Write([]byte(f.tpl))
-	default:
-		w.WriteRune('"')
-		w.WriteString(f.tpl)
-		w.WriteRune('"')
+// readContext gets the template from context and execute it.
+//
+// Returns the typed fields to write on log
+func readContext(c echo.Context) []zapcore.Field {
+	t := getTemplate(c)
+	if t == nil {
+		return nil
 	}
+
+	return t.execute(c)
 }
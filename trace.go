@@ -0,0 +1,408 @@
+package logecho
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderTraceparent and HeaderTracestate are the W3C Trace
+	// Context headers
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+
+	// HeaderB3 is the Zipkin B3 single header, and the HeaderB3 ones
+	// below are the Zipkin B3 multi headers
+	HeaderB3             = "b3"
+	HeaderB3TraceID      = "X-B3-TraceId"
+	HeaderB3SpanID       = "X-B3-SpanId"
+	HeaderB3ParentSpanID = "X-B3-ParentSpanId"
+	HeaderB3Sampled      = "X-B3-Sampled"
+	HeaderB3Flags        = "X-B3-Flags"
+
+	// traceKey is the echo.Context key where the middleware stores
+	// the request traceContext
+	traceKey = "logecho.trace"
+
+	// maxTracestateLength is the longest tracestate propagated
+	maxTracestateLength = 512
+)
+
+// traceSampled is the sampling decision of a trace
+type traceSampled uint8
+
+const (
+	sampledDeferred traceSampled = iota // no decision was made by the caller
+	sampledNo
+	sampledYes
+	sampledDebug
+)
+
+// traceContext is the trace of a request. spanID is generated to each
+// request, parentSpanID is the span id of the caller
+type traceContext struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	sampled      traceSampled
+	flags        byte
+	state        string
+}
+
+// newTraceContext continues parent in a new span. A nil parent, or a
+// parent with only the sampling decision, starts a new trace
+func newTraceContext(parent *traceContext) *traceContext {
+	if parent == nil {
+		parent = &traceContext{}
+	}
+
+	trace := &traceContext{
+		traceID:      parent.traceID,
+		spanID:       randomHex(8),
+		parentSpanID: parent.spanID,
+		sampled:      parent.sampled,
+		flags:        parent.flags,
+		state:        parent.state,
+	}
+
+	if trace.traceID == "" {
+		trace.traceID = randomHex(16)
+	}
+
+	return trace
+}
+
+// extractW3C parses the traceparent and tracestate headers. It returns
+// nil when traceparent is missing or invalid, and then tracestate is
+// discarded too
+func extractW3C(h http.Header) *traceContext {
+	parts := strings.Split(h.Get(HeaderTraceparent), "-")
+	if len(parts) < 4 {
+		return nil
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// version 00 has exactly 4 parts. Later versions can append parts
+	if !isHex(version, 2) || version == "ff" || version == "00" && len(parts) != 4 {
+		return nil
+	}
+
+	if !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(flags, 2) || isZeroHex(traceID) || isZeroHex(spanID) {
+		return nil
+	}
+
+	f, _ := hex.DecodeString(flags)
+
+	sampled := sampledNo
+	if f[0]&1 == 1 {
+		sampled = sampledYes
+	}
+
+	return &traceContext{
+		traceID: traceID,
+		spanID:  spanID,
+		sampled: sampled,
+		flags:   f[0],
+		state:   tracestate(h),
+	}
+}
+
+// injectW3C sets trace as traceparent and tracestate headers of h
+func injectW3C(h http.Header, trace *traceContext) {
+	flags := trace.flags &^ 1
+	if trace.sampled == sampledYes || trace.sampled == sampledDebug {
+		flags |= 1
+	}
+
+	h.Set(HeaderTraceparent, "00-"+trace.traceID+"-"+trace.spanID+"-"+hex.EncodeToString([]byte{flags}))
+	if trace.state != "" {
+		h.Set(HeaderTracestate, trace.state)
+	}
+}
+
+// tracestate joins the tracestate headers of h. It is empty when they
+// are too long or have chars out of printable ASCII
+func tracestate(h http.Header) string {
+	state := strings.Join(h.Values(HeaderTracestate), ",")
+	if len(state) > maxTracestateLength {
+		return ""
+	}
+
+	for i := 0; i < len(state); i++ {
+		if state[i] < 0x20 || state[i] > 0x7e {
+			return ""
+		}
+	}
+
+	return state
+}
+
+// extractB3 parses the b3 single header. It returns nil when b3 is
+// missing or invalid. A b3 with only the sampling state starts a new
+// trace with it
+func extractB3(h http.Header) *traceContext {
+	b3 := h.Get(HeaderB3)
+	if b3 == "" {
+		return nil
+	}
+
+	parts := strings.Split(b3, "-")
+	if len(parts) == 1 {
+		sampled, ok := parseB3Sampled(parts[0])
+		if !ok {
+			return nil
+		}
+
+		return &traceContext{sampled: sampled}
+	}
+
+	if len(parts) > 4 {
+		return nil
+	}
+
+	trace := b3Trace(parts[0], parts[1])
+	if trace == nil {
+		return nil
+	}
+
+	if len(parts) > 2 {
+		sampled, ok := parseB3Sampled(parts[2])
+		if !ok {
+			return nil
+		}
+		trace.sampled = sampled
+	}
+
+	if len(parts) > 3 && !isHex(parts[3], 16) {
+		return nil
+	}
+
+	return trace
+}
+
+// injectB3 sets trace as b3 single header of h
+func injectB3(h http.Header, trace *traceContext) {
+	b3 := trace.traceID + "-" + trace.spanID
+	if sampled := formatB3Sampled(trace.sampled); sampled != "" {
+		b3 += "-" + sampled
+		if trace.parentSpanID != "" {
+			b3 += "-" + trace.parentSpanID
+		}
+	}
+
+	h.Set(HeaderB3, b3)
+}
+
+// extractB3Multi parses the X-B3-* headers. It returns nil when they
+// are missing or invalid. Only the sampling headers start a new trace
+// with it
+func extractB3Multi(h http.Header) *traceContext {
+	traceID, spanID := h.Get(HeaderB3TraceID), h.Get(HeaderB3SpanID)
+	sampledHeader, flags := h.Get(HeaderB3Sampled), h.Get(HeaderB3Flags)
+
+	sampled := sampledDeferred
+	switch {
+	case flags == "1":
+		sampled = sampledDebug
+	case sampledHeader == "1" || sampledHeader == "true":
+		sampled = sampledYes
+	case sampledHeader == "0" || sampledHeader == "false":
+		sampled = sampledNo
+	case sampledHeader != "":
+		return nil
+	}
+
+	if traceID == "" && spanID == "" {
+		if sampled == sampledDeferred {
+			return nil
+		}
+
+		return &traceContext{sampled: sampled}
+	}
+
+	trace := b3Trace(traceID, spanID)
+	if trace == nil {
+		return nil
+	}
+	trace.sampled = sampled
+
+	return trace
+}
+
+// injectB3Multi sets trace as X-B3-* headers of h
+func injectB3Multi(h http.Header, trace *traceContext) {
+	h.Set(HeaderB3TraceID, trace.traceID)
+	h.Set(HeaderB3SpanID, trace.spanID)
+	if trace.parentSpanID != "" {
+		h.Set(HeaderB3ParentSpanID, trace.parentSpanID)
+	}
+
+	switch trace.sampled {
+	case sampledDebug:
+		h.Set(HeaderB3Flags, "1")
+	case sampledYes:
+		h.Set(HeaderB3Sampled, "1")
+	case sampledNo:
+		h.Set(HeaderB3Sampled, "0")
+	}
+}
+
+// b3Trace validates B3 trace id and span id. 64 bits trace ids are
+// left padded to 128 bits, as W3C needs
+func b3Trace(traceID, spanID string) *traceContext {
+	if isHex(traceID, 16) {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+
+	if !isHex(traceID, 32) || !isHex(spanID, 16) || isZeroHex(traceID) || isZeroHex(spanID) {
+		return nil
+	}
+
+	return &traceContext{traceID: traceID, spanID: spanID}
+}
+
+// parseB3Sampled parses the B3 sampling state
+func parseB3Sampled(s string) (traceSampled, bool) {
+	switch s {
+	case "0":
+		return sampledNo, true
+	case "1":
+		return sampledYes, true
+	case "d":
+		return sampledDebug, true
+	default:
+		return sampledDeferred, false
+	}
+}
+
+// formatB3Sampled formats the B3 sampling state. It is empty when
+// sampling is deferred
+func formatB3Sampled(sampled traceSampled) string {
+	switch sampled {
+	case sampledNo:
+		return "0"
+	case sampledYes:
+		return "1"
+	case sampledDebug:
+		return "d"
+	default:
+		return ""
+	}
+}
+
+// isHex checks s is lowercase hex with n chars
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isZeroHex checks s has only zeros, an invalid trace id and span id
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// randomHex returns n crypto random bytes as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	readRandom(b)
+
+	return hex.EncodeToString(b)
+}
+
+// getTrace recovers the request traceContext from echo.Context. It
+// returns nil when EnableTraceContext is disabled
+func getTrace(c echo.Context) *traceContext {
+	trace, _ := c.Get(traceKey).(*traceContext)
+	return trace
+}
+
+func getTraceID(c echo.Context) string {
+	if trace := getTrace(c); trace != nil {
+		return trace.traceID
+	}
+
+	return ""
+}
+
+func getSpanID(c echo.Context) string {
+	if trace := getTrace(c); trace != nil {
+		return trace.spanID
+	}
+
+	return ""
+}
+
+func getParentSpanID(c echo.Context) string {
+	if trace := getTrace(c); trace != nil {
+		return trace.parentSpanID
+	}
+
+	return ""
+}
+
+// getTraceSampled returns if the trace is sampled. It is not ok when
+// the caller deferred the decision
+func getTraceSampled(c echo.Context) (sampled bool, ok bool) {
+	trace := getTrace(c)
+	if trace == nil || trace.sampled == sampledDeferred {
+		return false, false
+	}
+
+	return trace.sampled != sampledNo, true
+}
+
+// traceFromContext recovers the request traceContext from ctx
+func traceFromContext(ctx context.Context) *traceContext {
+	if scope := getScope(ctx); scope != nil {
+		return scope.trace
+	}
+
+	return nil
+}
+
+// TraceIDFromContext returns the trace id of the request handled by the
+// middleware. It is empty when EnableTraceContext is disabled or ctx is
+// not from a request handled by the middleware
+func TraceIDFromContext(ctx context.Context) string {
+	if trace := traceFromContext(ctx); trace != nil {
+		return trace.traceID
+	}
+
+	return ""
+}
+
+// SpanIDFromContext returns the span id generated to the request
+// handled by the middleware. It is empty when EnableTraceContext is
+// disabled or ctx is not from a request handled by the middleware
+func SpanIDFromContext(ctx context.Context) string {
+	if trace := traceFromContext(ctx); trace != nil {
+		return trace.spanID
+	}
+
+	return ""
+}
+
+// ParentSpanIDFromContext returns the span id of the caller of the
+// request handled by the middleware. It is empty when the request came
+// without a trace
+func ParentSpanIDFromContext(ctx context.Context) string {
+	if trace := traceFromContext(ctx); trace != nil {
+		return trace.parentSpanID
+	}
+
+	return ""
+}
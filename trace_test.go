@@ -0,0 +1,290 @@
+package logecho
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestTraceContext(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	var traceID, spanID, parentSpanID string
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:             newTestLogger(b),
+		EnableTraceContext: true,
+		Fields: Fields{
+			"trace_id":       TraceID,
+			"span_id":        SpanID,
+			"parent_span_id": ParentSpanID,
+		},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		traceID, spanID, parentSpanID = TraceIDFromContext(ctx), SpanIDFromContext(ctx), ParentSpanIDFromContext(ctx)
+		FromContext(ctx).Info("from context")
+		return c.NoContent(http.StatusOK)
+	})
+
+	t.Run("should continue incoming trace in a new span", func(t *testing.T) {
+		b.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderTraceparent, "00-"+testTraceID+"-"+testSpanID+"-01")
+		req.Header.Add(HeaderTracestate, "congo=t61rcWkgMzE")
+		req.Header.Add(HeaderTracestate, "rojo=00f067aa0ba902b7")
+		rec := serve(e, req)
+
+		if traceID != testTraceID || parentSpanID != testSpanID {
+			t.Fatal("expected trace", testTraceID, "from span", testSpanID, "but got", traceID, parentSpanID)
+		}
+		if !isHex(spanID, 16) || spanID == testSpanID {
+			t.Fatal("expected a new span id but got", spanID)
+		}
+
+		if got := rec.Header().Get(HeaderTraceparent); got != "00-"+testTraceID+"-"+spanID+"-01" {
+			t.Fatal("expected response traceparent of the new span but got", got)
+		}
+		if got := rec.Header().Get(HeaderTracestate); got != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+			t.Fatal("expected tracestate propagated but got", got)
+		}
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 {
+			t.Fatal("expected 2 log lines but got", lines)
+		}
+		if lines[0]["trace_id"] != traceID || lines[0]["span_id"] != spanID {
+			t.Fatal("expected trace ids on context logger but got", lines[0])
+		}
+		handled := lines[1]
+		if handled["trace_id"] != traceID || handled["span_id"] != spanID || handled["parent_span_id"] != testSpanID {
+			t.Fatal("expected the trace on fields but got", handled)
+		}
+	})
+
+	invalid := []string{
+		"",
+		"00-" + testTraceID + "-" + testSpanID,
+		"00-" + strings.ToUpper(testTraceID) + "-" + testSpanID + "-01",
+		"00-" + strings.Repeat("0", 32) + "-" + testSpanID + "-01",
+		"00-" + testTraceID + "-" + strings.Repeat("0", 16) + "-01",
+		"ff-" + testTraceID + "-" + testSpanID + "-01",
+		"00-" + testTraceID + "-" + testSpanID + "-01-extra",
+		"00-" + testTraceID + "-" + testSpanID + "-1",
+	}
+
+	for _, traceparent := range invalid {
+		t.Run("should start a new trace to "+traceparent, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderTraceparent, traceparent)
+			req.Header.Set(HeaderTracestate, "congo=t61rcWkgMzE")
+			rec := serve(e, req)
+
+			if !isHex(traceID, 32) || traceID == testTraceID || parentSpanID != "" {
+				t.Fatal("expected a new trace without parent but got", traceID, parentSpanID)
+			}
+			if got := rec.Header().Get(HeaderTraceparent); got != "00-"+traceID+"-"+spanID+"-00" {
+				t.Fatal("expected response traceparent of the new trace but got", got)
+			}
+			if got := rec.Header().Get(HeaderTracestate); got != "" {
+				t.Fatal("expected tracestate discarded but got", got)
+			}
+		})
+	}
+
+	t.Run("should accept later versions", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderTraceparent, "01-"+testTraceID+"-"+testSpanID+"-01-future")
+		serve(e, req)
+
+		if traceID != testTraceID || parentSpanID != testSpanID {
+			t.Fatal("expected trace", testTraceID, "from span", testSpanID, "but got", traceID, parentSpanID)
+		}
+	})
+}
+
+func TestTraceContextDisabled(t *testing.T) {
+	useLogger(t, newTestLogger(new(bytes.Buffer)))
+
+	var traceID string
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/", func(c echo.Context) error {
+		traceID = TraceIDFromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+	if traceID != "" || rec.Header().Get(HeaderTraceparent) != "" {
+		t.Fatal("expected trace context disabled but got", traceID)
+	}
+
+	if TraceIDFromContext(context.Background()) != "" || SpanIDFromContext(context.Background()) != "" {
+		t.Fatal("expected no trace out of a request")
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	const otherTraceID, otherSpanID = "80f198ee56343ba864fe8b2a57d3eff7", "e457b5a2e4d86bd1"
+
+	b := new(bytes.Buffer)
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:             newTestLogger(b),
+		EnableTraceContext: true,
+		Propagation:        []Propagation{PropagationB3, PropagationW3C, PropagationB3Multi},
+		Fields: Fields{
+			"trace_id":       TraceID,
+			"span_id":        SpanID,
+			"parent_span_id": ParentSpanID,
+			"sampled":        TraceSampled,
+		},
+	}))
+	e.GET("/", okHandler)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		traceID string
+		parent  string
+		sampled interface{}
+	}{
+		{
+			name:    "b3 single",
+			headers: map[string]string{HeaderB3: otherTraceID + "-" + otherSpanID + "-1-" + testSpanID},
+			traceID: otherTraceID, parent: otherSpanID, sampled: true,
+		},
+		{
+			name: "first format present wins",
+			headers: map[string]string{
+				HeaderB3:          otherTraceID + "-" + otherSpanID,
+				HeaderTraceparent: "00-" + testTraceID + "-" + testSpanID + "-01",
+			},
+			traceID: otherTraceID, parent: otherSpanID,
+		},
+		{
+			name: "invalid format is skipped",
+			headers: map[string]string{
+				HeaderB3:          "not-a-trace",
+				HeaderTraceparent: "00-" + testTraceID + "-" + testSpanID + "-00",
+			},
+			traceID: testTraceID, parent: testSpanID, sampled: false,
+		},
+		{
+			name: "b3 multi with 64 bits trace id",
+			headers: map[string]string{
+				HeaderB3TraceID: "a3ce929d0e0e4736",
+				HeaderB3SpanID:  otherSpanID,
+				HeaderB3Sampled: "0",
+			},
+			traceID: "0000000000000000a3ce929d0e0e4736", parent: otherSpanID, sampled: false,
+		},
+		{
+			name:    "b3 debug",
+			headers: map[string]string{HeaderB3TraceID: otherTraceID, HeaderB3SpanID: otherSpanID, HeaderB3Flags: "1"},
+			traceID: otherTraceID, parent: otherSpanID, sampled: true,
+		},
+		{
+			name:    "b3 sampling only",
+			headers: map[string]string{HeaderB3: "0"},
+			sampled: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := serve(e, req)
+
+			lines := decodeLines(t, b)
+			if len(lines) != 1 {
+				t.Fatal("expected 1 log line but got", lines)
+			}
+			line := lines[0]
+
+			traceID, spanID := line["trace_id"].(string), line["span_id"].(string)
+			if tc.traceID != "" && traceID != tc.traceID || !isHex(traceID, 32) {
+				t.Fatal("expected trace id", tc.traceID, "but got", traceID)
+			}
+			if line["parent_span_id"] != tc.parent {
+				t.Fatal("expected parent span id", tc.parent, "but got", line["parent_span_id"])
+			}
+			if sampled, ok := line["sampled"]; sampled != tc.sampled || ok != (tc.sampled != nil) {
+				t.Fatal("expected sampled", tc.sampled, "but got", sampled)
+			}
+
+			h := rec.Header()
+			if !strings.HasPrefix(h.Get(HeaderB3), traceID+"-"+spanID) {
+				t.Fatal("expected b3 of the new span but got", h.Get(HeaderB3))
+			}
+			if !strings.HasPrefix(h.Get(HeaderTraceparent), "00-"+traceID+"-"+spanID+"-") {
+				t.Fatal("expected traceparent of the new span but got", h.Get(HeaderTraceparent))
+			}
+			if h.Get(HeaderB3TraceID) != traceID || h.Get(HeaderB3SpanID) != spanID || h.Get(HeaderB3ParentSpanID) != tc.parent {
+				t.Fatal("expected X-B3 headers of the new span but got", h)
+			}
+		})
+	}
+}
+
+func TestInjectSampled(t *testing.T) {
+	cases := []struct {
+		sampled     traceSampled
+		b3          string
+		traceparent string
+		multi       map[string]string
+	}{
+		{sampledDeferred, "t-s", "00-t-s-00", map[string]string{}},
+		{sampledNo, "t-s-0-p", "00-t-s-00", map[string]string{HeaderB3Sampled: "0"}},
+		{sampledYes, "t-s-1-p", "00-t-s-01", map[string]string{HeaderB3Sampled: "1"}},
+		{sampledDebug, "t-s-d-p", "00-t-s-01", map[string]string{HeaderB3Flags: "1"}},
+	}
+
+	for _, tc := range cases {
+		trace := &traceContext{traceID: "t", spanID: "s", sampled: tc.sampled}
+		if tc.sampled != sampledDeferred {
+			trace.parentSpanID = "p"
+		}
+
+		h := http.Header{}
+		injectB3(h, trace)
+		injectW3C(h, trace)
+		injectB3Multi(h, trace)
+
+		if h.Get(HeaderB3) != tc.b3 || h.Get(HeaderTraceparent) != tc.traceparent {
+			t.Fatal("expected b3", tc.b3, "and traceparent", tc.traceparent, "to sampled", tc.sampled, "but got", h)
+		}
+		for _, key := range []string{HeaderB3Sampled, HeaderB3Flags} {
+			if h.Get(key) != tc.multi[key] {
+				t.Fatal("expected", key, tc.multi[key], "to sampled", tc.sampled, "but got", h.Get(key))
+			}
+		}
+	}
+}
+
+func TestUnknownPropagation(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected MiddlewareWithConfig panic to unknown propagation")
+		}
+	}()
+
+	MiddlewareWithConfig(MiddlewareConfig{EnableTraceContext: true, Propagation: []Propagation{"jaeger"}})
+}
@@ -0,0 +1,173 @@
+package logecho
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// outgoingHeaders are the headers of a middleware who Transport sets on
+// outbound requests
+type outgoingHeaders struct {
+	requestID     string
+	transactionID string
+	propagation   []propagator
+}
+
+// transport is the http.RoundTripper returned by Transport
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps base into a http.RoundTripper who propagates the
+// request id, the transaction id and the trace of the request handled
+// by the middleware to outbound requests, and logs each outbound call
+// with the request fields, like FromContext.
+//
+// The call is logged when the response body is read to the end or
+// closed, with the bytes read as response_bytes, so response bodies
+// should be closed as usual. 5xx responses are logged at ERROR and
+// 4xx at WARN.
+//
+// The request handled by the middleware is found from the outbound
+// request context, so handlers should pass it on:
+//
+//	client := &http.Client{Transport: logecho.Transport(nil)}
+//
+//	func handler(c echo.Context) error {
+//		req, _ := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, url, nil)
+//		resp, err := client.Do(req)
+//		// ...
+//	}
+//
+// Headers already set on the outbound request are kept. A nil base is
+// http.DefaultTransport
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scope := getScope(req.Context())
+	if scope != nil && scope.outgoing != nil {
+		req = propagate(req, scope)
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	fields := []zapcore.Field{
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.Redacted()),
+		zap.Duration("latency", latency),
+	}
+
+	if req.ContentLength > 0 {
+		fields = append(fields, zap.Int64("request_bytes", req.ContentLength))
+	}
+
+	l := FromContext(req.Context())
+	if err != nil {
+		l.Error("outbound request", append(fields, zap.Error(err))...)
+		return resp, err
+	}
+
+	fields = append(fields, zap.Int("status", resp.StatusCode))
+	level := outboundLevel(resp.StatusCode)
+
+	// a 101 body is the upgraded connection, who is not counted
+	if resp.StatusCode == http.StatusSwitchingProtocols || resp.Body == nil {
+		l.zl.Check(level, "outbound request").Write(fields...)
+		return resp, nil
+	}
+
+	resp.Body = &countingBody{ReadCloser: resp.Body, done: func(n int64) {
+		l.zl.Check(level, "outbound request").Write(append(fields, zap.Int64("response_bytes", n))...)
+	}}
+
+	return resp, nil
+}
+
+// outboundLevel is the outbound request log level by status. It
+// matches DefaultHandledLog: 5xx as ERROR, 4xx as WARN and INFO else
+func outboundLevel(status int) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zap.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zap.WarnLevel
+	default:
+		return zap.InfoLevel
+	}
+}
+
+// countingBody counts the response body bytes read. It calls done
+// once, when the body is read to the end or closed, so chunked and
+// unknown length responses log their size too
+type countingBody struct {
+	io.ReadCloser
+
+	n    int64
+	once sync.Once
+	done func(n int64)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	if err == io.EOF {
+		b.finish()
+	}
+
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+
+	return err
+}
+
+func (b *countingBody) finish() {
+	b.once.Do(func() { b.done(atomic.LoadInt64(&b.n)) })
+}
+
+// propagate returns a copy of req with the correlation headers of
+// scope. A http.RoundTripper must not modify req, so it is cloned
+func propagate(req *http.Request, scope *requestScope) *http.Request {
+	h := http.Header{}
+	if scope.requestID != "" {
+		h.Set(scope.outgoing.requestID, scope.requestID)
+	}
+	if scope.transactionID != "" {
+		h.Set(scope.outgoing.transactionID, scope.transactionID)
+	}
+	if scope.trace != nil {
+		for _, format := range scope.outgoing.propagation {
+			format.inject(h, scope.trace)
+		}
+	}
+
+	out := req.Clone(req.Context())
+	if out.Header == nil {
+		out.Header = http.Header{}
+	}
+
+	for key, values := range h {
+		if out.Header.Get(key) == "" {
+			out.Header[key] = values
+		}
+	}
+
+	return out
+}
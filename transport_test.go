@@ -0,0 +1,200 @@
+package logecho
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// upstream is a httptest.Server who records the headers of the last
+// request
+func upstream(t *testing.T) (*httptest.Server, *http.Header) {
+	got := new(http.Header)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = r.Header.Clone()
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/chunked":
+			// flushing before the end makes an unknown length response
+			io.WriteString(w, "chunk-1")
+			w.(http.Flusher).Flush()
+			io.WriteString(w, "chunk-2")
+		default:
+			io.WriteString(w, "pong")
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, got
+}
+
+func TestTransport(t *testing.T) {
+	srv, got := upstream(t)
+	client := &http.Client{Transport: Transport(nil)}
+
+	b := new(bytes.Buffer)
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger:             newTestLogger(b),
+		EnableRequestID:    true,
+		EnableTraceContext: true,
+		Propagation:        []Propagation{PropagationW3C, PropagationB3},
+		TransactionIDHeader: CorrelationHeader{
+			Name: "X-Tx",
+		},
+	}))
+	e.GET("/*", func(c echo.Context) error {
+		With(c, zap.String("user_id", "1"))
+
+		req, _ := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, srv.URL+c.Request().URL.Path, nil)
+		if c.QueryParam("own") != "" {
+			req.Header.Set(echo.HeaderXRequestID, "own-id")
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.ReadAll(resp.Body)
+
+		if req.Header.Get("X-Tx") != "" {
+			// the handler runs out of the subtests, so it can not stop them
+			t.Error("expected outbound request not modified but got", req.Header.Get("X-Tx"))
+		}
+
+		return c.NoContent(resp.StatusCode)
+	})
+
+	t.Run("should propagate ids and log the call", func(t *testing.T) {
+		b.Reset()
+
+		rec := serve(e, httptest.NewRequest(http.MethodGet, "/ping", nil))
+		requestID, traceID := rec.Header().Get(echo.HeaderXRequestID), getTraceIDFromHeader(rec.Header())
+
+		if got.Get(echo.HeaderXRequestID) != requestID || got.Get("X-Tx") != rec.Header().Get("X-Tx") {
+			t.Fatal("expected correlation headers on outbound request but got", *got)
+		}
+		if got.Get(HeaderTraceparent) != rec.Header().Get(HeaderTraceparent) || got.Get(HeaderB3) != rec.Header().Get(HeaderB3) {
+			t.Fatal("expected trace headers of the request span on outbound request but got", *got)
+		}
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 {
+			t.Fatal("expected outbound and handled logs but got", lines)
+		}
+
+		line := lines[0]
+		if line["msg"] != "outbound request" || line["level"] != "info" {
+			t.Fatal("expected info outbound request log but got", line)
+		}
+		if line["method"] != http.MethodGet || line["url"] != srv.URL+"/ping" || line["status"] != jsonNumber(http.StatusOK) || line["response_bytes"] != jsonNumber(4) {
+			t.Fatal("expected call fields on outbound log but got", line)
+		}
+		if _, ok := line["latency"]; !ok {
+			t.Fatal("expected latency on outbound log but got", line)
+		}
+		if line["request_id"] != requestID || line["trace_id"] != traceID || line["user_id"] != "1" {
+			t.Fatal("expected request fields on outbound log but got", line)
+		}
+	})
+
+	t.Run("should keep outbound headers already set", func(t *testing.T) {
+		serve(e, httptest.NewRequest(http.MethodGet, "/ping?own=1", nil))
+
+		if got.Get(echo.HeaderXRequestID) != "own-id" {
+			t.Fatal("expected own request id but got", got.Get(echo.HeaderXRequestID))
+		}
+	})
+
+	t.Run("should log upstream status", func(t *testing.T) {
+		b.Reset()
+
+		rec := serve(e, httptest.NewRequest(http.MethodGet, "/fail", nil))
+		if rec.Code != http.StatusBadGateway {
+			t.Fatal("expected upstream status but got", rec.Code)
+		}
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 || lines[0]["status"] != jsonNumber(http.StatusBadGateway) || lines[0]["level"] != "error" {
+			t.Fatal("expected upstream status at error on outbound log but got", lines)
+		}
+	})
+
+	t.Run("should count bytes of unknown length responses", func(t *testing.T) {
+		b.Reset()
+
+		serve(e, httptest.NewRequest(http.MethodGet, "/chunked", nil))
+
+		lines := decodeLines(t, b)
+		if len(lines) != 2 || lines[0]["response_bytes"] != jsonNumber(len("chunk-1chunk-2")) {
+			t.Fatal("expected counted response bytes on outbound log but got", lines)
+		}
+	})
+}
+
+func TestTransportError(t *testing.T) {
+	srv, _ := upstream(t)
+	url := srv.URL
+	srv.Close()
+
+	b := new(bytes.Buffer)
+	useLogger(t, newTestLogger(b))
+
+	client := &http.Client{Transport: Transport(nil)}
+	if _, err := client.Post(url+"/down", "text/plain", strings.NewReader("ping")); err == nil {
+		t.Fatal("expected error from closed server")
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 {
+		t.Fatal("expected outbound log through Logger but got", lines)
+	}
+
+	line := lines[0]
+	if line["level"] != "error" || line["method"] != http.MethodPost || line["request_bytes"] != jsonNumber(4) {
+		t.Fatal("expected error outbound log of the POST but got", line)
+	}
+	if line["error"] == nil || line["status"] != nil {
+		t.Fatal("expected error without status but got", line)
+	}
+}
+
+func TestTransportRedactsURL(t *testing.T) {
+	srv, got := upstream(t)
+
+	b := new(bytes.Buffer)
+	useLogger(t, newTestLogger(b))
+
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	resp, err := client.Get(strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + "/ping")
+	if err != nil {
+		t.Fatal("expected no error but got", err)
+	}
+	resp.Body.Close()
+
+	if got.Get(echo.HeaderXRequestID) != "" || got.Get(HeaderTraceparent) != "" {
+		t.Fatal("expected no correlation headers out of a request but got", *got)
+	}
+
+	lines := decodeLines(t, b)
+	if len(lines) != 1 || strings.Contains(lines[0]["url"].(string), "secret") {
+		t.Fatal("expected redacted url but got", lines)
+	}
+}
+
+// getTraceIDFromHeader reads the trace id of a traceparent header
+func getTraceIDFromHeader(h http.Header) string {
+	if trace := extractW3C(h); trace != nil {
+		return trace.traceID
+	}
+
+	return ""
+}
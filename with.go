@@ -0,0 +1,61 @@
+package logecho
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldsKey is the echo.Context key where With stores the request
+// scoped fields
+const fieldsKey = "logecho.fields"
+
+// With binds fields to the request. Every later log call with the
+// same echo.Context, including the "handled request" log written by
+// the middleware, carries them.
+//
+// Fields bound by With override template fields with the same key,
+// and are overridden by fields passed to the log call. Calling With
+// again with a bound key replaces its value.
+//
+// On requests handled by the middleware the fields are bound to the
+// request context.Context too, so FromContext carries them.
+//
+// Example:
+//
+//	func auth(next echo.HandlerFunc) echo.HandlerFunc {
+//		return func(c echo.Context) error {
+//			// ... authenticate
+//			logecho.With(c, zap.String("user_id", user.ID))
+//			return next(c)
+//		}
+//	}
+func With(c echo.Context, fields ...zapcore.Field) {
+	if len(fields) == 0 {
+		return
+	}
+
+	if scope := getScope(c.Request().Context()); scope != nil {
+		scope.bind(fields)
+		return
+	}
+
+	c.Set(fieldsKey, mergeFields(getBoundFields(c), fields))
+}
+
+// With binds fields to the request like the package level With
+func (z *Logecho) With(c echo.Context, fields ...zapcore.Field) {
+	With(c, fields...)
+}
+
+// getBoundFields returns the fields bound to the request by With
+func getBoundFields(c echo.Context) []zapcore.Field {
+	if scope := getScope(c.Request().Context()); scope != nil {
+		return scope.boundFields()
+	}
+
+	if fields, ok := c.Get(fieldsKey).([]zapcore.Field); ok {
+		return fields
+	}
+
+	return nil
+}
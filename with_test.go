@@ -0,0 +1,90 @@
+package logecho
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestWith(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			With(c, zap.String("user_id", "1"), zap.String("tenant", "a"))
+			return next(c)
+		}
+	}
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(MiddlewareConfig{
+		Logger: l,
+		Fields: Fields{"method": Method, "tenant": Host},
+	}), auth)
+	e.GET("/", func(c echo.Context) error {
+		l.Info(c, "before lookup")
+		l.With(c, zap.String("tenant", "b"))
+		l.Info(c, "after lookup", zap.String("user_id", "2"))
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := decodeLines(t, b)
+	if len(lines) != 3 {
+		t.Fatal("expected 3 log lines but got", len(lines))
+	}
+
+	expected := []struct{ userID, tenant string }{
+		{"1", "a"}, // bound fields override template fields
+		{"2", "b"}, // call fields override bound fields
+		{"1", "b"}, // handled request carries bound fields
+	}
+
+	for i, e := range expected {
+		if lines[i]["user_id"] != e.userID || lines[i]["tenant"] != e.tenant {
+			t.Fatal("expected user_id", e.userID, "and tenant", e.tenant, "but got", lines[i])
+		}
+		if lines[i]["method"] != http.MethodGet {
+			t.Fatal("expected template fields but got", lines[i])
+		}
+	}
+}
+
+func TestWithBeforeMiddleware(t *testing.T) {
+	b := new(bytes.Buffer)
+	l := newTestLogger(b)
+
+	outer := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			With(c, zap.String("user_id", "42"))
+			return next(c)
+		}
+	}
+
+	e := echo.New()
+	e.Use(outer, MiddlewareWithConfig(MiddlewareConfig{Logger: l}))
+	e.GET("/", func(c echo.Context) error {
+		With(c, zap.String("tenant", "a"))
+		FromContext(c.Request().Context()).Info("from context")
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := decodeLines(t, b)
+	if len(lines) != 2 {
+		t.Fatal("expected 2 log lines but got", len(lines))
+	}
+
+	for _, line := range lines {
+		if line["user_id"] != "42" || line["tenant"] != "a" {
+			t.Fatal("expected fields bound before and after the middleware but got", line)
+		}
+	}
+}